@@ -2,11 +2,14 @@ module github.com/guyskk/ccc
 
 go 1.25
 
-require github.com/twpayne/go-expect v0.0.2-0.20241130000624-916db2914efd
+require (
+	github.com/twpayne/go-expect v0.0.2-0.20241130000624-916db2914efd
+	golang.org/x/sys v0.15.0
+	golang.org/x/term v0.15.0
+)
 
 require (
 	github.com/creack/pty/v2 v2.0.0-20231209135443-03db72c7b76c // indirect
 	github.com/muesli/cancelreader v0.2.2 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
-	golang.org/x/sys v0.15.0 // indirect
 )
@@ -0,0 +1,238 @@
+// Package bench measures the latency and throughput of providers by sending
+// /v1/messages requests, optionally streamed, with bounded concurrency.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+// DefaultPrompt is sent when no prompts are configured.
+const DefaultPrompt = "Count from 1 to 50, separated by spaces."
+
+// Options configures a benchmark run.
+type Options struct {
+	Requests    int           // Number of requests per provider
+	Concurrency int           // Maximum number of requests in flight per provider
+	Stream      bool          // Use streaming responses, required for time-to-first-token
+	Prompts     []string      // Prompts, used in turn; DefaultPrompt if empty
+	MaxTokens   int           // max_tokens of each request
+	Timeout     time.Duration // Timeout of each request
+}
+
+// Target is a provider endpoint to benchmark.
+type Target struct {
+	Provider string
+	Endpoint validate.Endpoint
+	Model    string
+}
+
+// Sample is the outcome of a single request.
+type Sample struct {
+	TTFT         time.Duration // Time to the first content delta, streaming only
+	Latency      time.Duration // Time to the complete response
+	OutputTokens int
+	Err          error
+}
+
+// Stats are latency percentiles in milliseconds.
+type Stats struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+// Result summarizes the samples of one provider.
+type Result struct {
+	Provider        string   `json:"provider"`
+	Model           string   `json:"model"`
+	Requests        int      `json:"requests"`
+	Errors          int      `json:"errors"`
+	ErrorRate       float64  `json:"error_rate"`
+	TTFT            *Stats   `json:"ttft_ms,omitempty"`
+	Latency         *Stats   `json:"latency_ms,omitempty"`
+	TokensPerSecond float64  `json:"output_tokens_per_second"`
+	ErrorMessages   []string `json:"error_messages,omitempty"` // Distinct errors, in order of occurrence
+}
+
+// Run benchmarks a target and summarizes the samples.
+func Run(ctx context.Context, target Target, opts Options) *Result {
+	return Summarize(target, Collect(ctx, target, opts))
+}
+
+// Collect sends opts.Requests requests to the target, at most
+// opts.Concurrency at a time, and returns the samples in request order.
+func Collect(ctx context.Context, target Target, opts Options) []Sample {
+	client, err := target.Endpoint.NewHTTPClient(opts.Timeout)
+	if err != nil {
+		samples := make([]Sample, opts.Requests)
+		for i := range samples {
+			samples[i] = Sample{Err: err}
+		}
+		return samples
+	}
+
+	prompts := opts.Prompts
+	if len(prompts) == 0 {
+		prompts = []string{DefaultPrompt}
+	}
+	concurrency := max(1, min(opts.Concurrency, opts.Requests))
+
+	samples := make([]Sample, opts.Requests)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range samples {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			samples[i] = send(ctx, client, target, prompts[i%len(prompts)], opts)
+		}(i)
+	}
+	wg.Wait()
+	return samples
+}
+
+// messagesResponse is the part of a /v1/messages response used for token counting.
+type messagesResponse struct {
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// send sends one request and measures it.
+func send(ctx context.Context, client *http.Client, target Target, prompt string, opts Options) Sample {
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      target.Model,
+		"max_tokens": opts.MaxTokens,
+		"stream":     opts.Stream,
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	})
+	if err != nil {
+		return Sample{Err: err}
+	}
+	req, err := validate.NewMessagesRequest(ctx, target.Endpoint, body)
+	if err != nil {
+		return Sample{Err: err}
+	}
+	if opts.Stream {
+		req.Header.Set("accept", "text/event-stream")
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Sample{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		if msg := strings.TrimSpace(string(buf)); msg != "" {
+			return Sample{Err: fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)}
+		}
+		return Sample{Err: fmt.Errorf("HTTP %d", resp.StatusCode)}
+	}
+
+	var sample Sample
+	if !opts.Stream {
+		var r messagesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			return Sample{Err: fmt.Errorf("invalid response: %w", err)}
+		}
+		sample.OutputTokens = r.Usage.OutputTokens
+		sample.Latency = time.Since(start)
+		return sample
+	}
+
+	err = validate.ReadEvents(resp.Body, func(event validate.Event) error {
+		switch event.Type {
+		case "content_block_delta":
+			if sample.TTFT == 0 {
+				sample.TTFT = time.Since(start)
+			}
+		case "message_delta":
+			// usage.output_tokens is cumulative
+			var r messagesResponse
+			if err := json.Unmarshal(event.Data, &r); err == nil && r.Usage.OutputTokens > 0 {
+				sample.OutputTokens = r.Usage.OutputTokens
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Sample{Err: err}
+	}
+	sample.Latency = time.Since(start)
+	if sample.TTFT == 0 {
+		return Sample{Err: fmt.Errorf("stream contained no content")}
+	}
+	return sample
+}
+
+// Summarize computes the result of a target's samples. Throughput is
+// the total number of output tokens divided by the total generation time,
+// which excludes the time to first token when streaming.
+func Summarize(target Target, samples []Sample) *Result {
+	result := &Result{
+		Provider: target.Provider,
+		Model:    target.Model,
+		Requests: len(samples),
+	}
+
+	var ttfts, latencies []time.Duration
+	var tokens int
+	var generation time.Duration
+	seen := make(map[string]bool)
+	for _, s := range samples {
+		if s.Err != nil {
+			result.Errors++
+			if msg := s.Err.Error(); !seen[msg] {
+				seen[msg] = true
+				result.ErrorMessages = append(result.ErrorMessages, msg)
+			}
+			continue
+		}
+		latencies = append(latencies, s.Latency)
+		if s.TTFT > 0 {
+			ttfts = append(ttfts, s.TTFT)
+		}
+		tokens += s.OutputTokens
+		generation += s.Latency - s.TTFT
+	}
+
+	if result.Requests > 0 {
+		result.ErrorRate = float64(result.Errors) / float64(result.Requests)
+	}
+	result.Latency = percentiles(latencies)
+	result.TTFT = percentiles(ttfts)
+	if generation > 0 {
+		result.TokensPerSecond = math.Round(float64(tokens)/generation.Seconds()*10) / 10
+	}
+	return result
+}
+
+// percentiles returns the nearest-rank percentiles of durations, or nil if there are none.
+func percentiles(durations []time.Duration) *Stats {
+	if len(durations) == 0 {
+		return nil
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) float64 {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		return float64(sorted[max(rank, 1)-1].Microseconds()) / 1000
+	}
+	return &Stats{P50: at(50), P90: at(90), P99: at(99)}
+}
@@ -0,0 +1,152 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+// newServer returns a fake Messages API that answers with 20 output tokens,
+// streamed when requested, and fails every failEvery-th request.
+func newServer(t *testing.T, failEvery int64, inFlight, maxInFlight *int64) *httptest.Server {
+	t.Helper()
+	var count int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(inFlight, 1)
+		defer atomic.AddInt64(inFlight, -1)
+		for {
+			m := atomic.LoadInt64(maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt64(maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if failEvery > 0 && atomic.AddInt64(&count, 1)%failEvery == 0 {
+			http.Error(w, `{"error":"overloaded"}`, http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Stream {
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"1 2 3"}],"usage":{"input_tokens":5,"output_tokens":20}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\"}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"1 2\"}}\n\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":20}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun(t *testing.T) {
+	for _, stream := range []bool{false, true} {
+		t.Run(fmt.Sprintf("stream=%v", stream), func(t *testing.T) {
+			var inFlight, maxInFlight int64
+			server := newServer(t, 4, &inFlight, &maxInFlight)
+			target := Target{Provider: "p", Endpoint: validate.Endpoint{BaseURL: server.URL, AuthToken: "t"}, Model: "m"}
+
+			result := Run(context.Background(), target, Options{
+				Requests: 8, Concurrency: 3, Stream: stream, MaxTokens: 32, Timeout: 5 * time.Second,
+			})
+			if maxInFlight > 3 {
+				t.Errorf("max requests in flight = %d, want <= 3", maxInFlight)
+			}
+			if result.Requests != 8 || result.Errors != 2 || result.ErrorRate != 0.25 {
+				t.Errorf("Requests = %d, Errors = %d, ErrorRate = %v", result.Requests, result.Errors, result.ErrorRate)
+			}
+			if len(result.ErrorMessages) != 1 || !strings.HasPrefix(result.ErrorMessages[0], "HTTP 503") {
+				t.Errorf("ErrorMessages = %q", result.ErrorMessages)
+			}
+			if result.Latency == nil || result.Latency.P50 < 10 || result.TokensPerSecond <= 0 {
+				t.Errorf("Latency = %+v, TokensPerSecond = %v", result.Latency, result.TokensPerSecond)
+			}
+			if (result.TTFT != nil) != stream {
+				t.Errorf("TTFT = %+v, want set only when streaming", result.TTFT)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	samples := []Sample{
+		{Latency: 400 * time.Millisecond, TTFT: 100 * time.Millisecond, OutputTokens: 30},
+		{Latency: 200 * time.Millisecond, TTFT: 100 * time.Millisecond, OutputTokens: 10},
+		{Latency: 300 * time.Millisecond, TTFT: 200 * time.Millisecond, OutputTokens: 20},
+		{Err: errors.New("timeout")},
+		{Err: errors.New("timeout")},
+	}
+	result := Summarize(Target{Provider: "p", Model: "m"}, samples)
+
+	// 60 tokens in 0.5s of generation (latency minus TTFT)
+	if result.TokensPerSecond != 120 {
+		t.Errorf("TokensPerSecond = %v, want 120", result.TokensPerSecond)
+	}
+	if *result.Latency != (Stats{P50: 300, P90: 400, P99: 400}) {
+		t.Errorf("Latency = %+v", *result.Latency)
+	}
+	if *result.TTFT != (Stats{P50: 100, P90: 200, P99: 200}) {
+		t.Errorf("TTFT = %+v", *result.TTFT)
+	}
+	if result.Errors != 2 || result.ErrorRate != 0.4 || len(result.ErrorMessages) != 1 {
+		t.Errorf("Errors = %d, ErrorRate = %v, ErrorMessages = %q", result.Errors, result.ErrorRate, result.ErrorMessages)
+	}
+
+	if result := Summarize(Target{}, []Sample{{Err: errors.New("x")}}); result.Latency != nil || result.TTFT != nil {
+		t.Errorf("all failed: Latency = %+v, TTFT = %+v, want nil", result.Latency, result.TTFT)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	results := []*Result{
+		{Provider: "kimi", Model: "k2", Requests: 10, Errors: 1, ErrorRate: 0.1,
+			TTFT: &Stats{P50: 310, P90: 420}, Latency: &Stats{P50: 1200, P90: 1500, P99: 1900},
+			TokensPerSecond: 45.2, ErrorMessages: []string{"HTTP 429"}},
+		{Provider: "glm", Model: "glm-4.6", Requests: 10, Errors: 10, ErrorRate: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatTable, results); err != nil {
+		t.Fatalf("WriteReport(table) error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"PROVIDER", "9/10", "310/420", "1200/1500/1900", "45.2", "0/10", "100%", "kimi: HTTP 429"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "kimi") > strings.Index(out, "glm") {
+		t.Errorf("results should keep their order:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := WriteReport(&buf, FormatJSON, results); err != nil {
+		t.Fatalf("WriteReport(json) error = %v", err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded[0]["ttft_ms"].(map[string]interface{})["p50"] != 310.0 || decoded[1]["ttft_ms"] != nil {
+		t.Errorf("JSON = %s", buf.String())
+	}
+
+	if err := WriteReport(&buf, "xml", results); err == nil {
+		t.Error("unknown format should fail")
+	}
+}
@@ -0,0 +1,77 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guyskk/ccc/internal/prettyjson"
+)
+
+// Output formats supported by WriteReport.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// WriteReport writes the results to w in the given format, in the order given.
+func WriteReport(w io.Writer, format string, results []*Result) error {
+	switch format {
+	case "", FormatTable:
+		writeTable(w, results)
+		return nil
+	case FormatJSON:
+		if results == nil {
+			results = []*Result{}
+		}
+		data, err := prettyjson.Marshal(results)
+		if err != nil {
+			return fmt.Errorf("failed to marshal benchmark results: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	default:
+		return fmt.Errorf("unknown format '%s' (supported: %s, %s)", format, FormatTable, FormatJSON)
+	}
+}
+
+// formatStats formats the given percentiles in milliseconds, or "-" without samples.
+func formatStats(s *Stats, p ...string) string {
+	if s == nil {
+		return "-"
+	}
+	values := map[string]float64{"p50": s.P50, "p90": s.P90, "p99": s.P99}
+	parts := make([]string, len(p))
+	for i, name := range p {
+		parts[i] = fmt.Sprintf("%.0f", values[name])
+	}
+	return strings.Join(parts, "/")
+}
+
+// writeTable writes the results as an aligned table, followed by the distinct errors.
+func writeTable(w io.Writer, results []*Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tMODEL\tOK\tERRORS\tTTFT p50/p90 (ms)\tLATENCY p50/p90/p99 (ms)\tTOKENS/S")
+	for _, r := range results {
+		tokensPerSecond := "-"
+		if r.TokensPerSecond > 0 {
+			tokensPerSecond = fmt.Sprintf("%.1f", r.TokensPerSecond)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%.0f%%\t%s\t%s\t%s\n",
+			r.Provider, r.Model, r.Requests-r.Errors, r.Requests, r.ErrorRate*100,
+			formatStats(r.TTFT, "p50", "p90"), formatStats(r.Latency, "p50", "p90", "p99"), tokensPerSecond)
+	}
+	tw.Flush()
+
+	header := true
+	for _, r := range results {
+		for _, msg := range r.ErrorMessages {
+			if header {
+				fmt.Fprintln(w, "\nErrors:")
+				header = false
+			}
+			fmt.Fprintf(w, "  %s: %s\n", r.Provider, msg)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/guyskk/ccc/internal/bench"
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/provider"
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+// benchTarget resolves the endpoint, token and model of a provider.
+// The model is --model, the provider's ANTHROPIC_MODEL, or the best
+// model from the provider's model list, in that order.
+func benchTarget(cfg *config.Config, name, model string) (bench.Target, error) {
+	name, err := provider.Resolve(cfg, name)
+	if err != nil {
+		return bench.Target{}, err
+	}
+	if failoverMembers(cfg, name) != nil {
+		return bench.Target{}, fmt.Errorf("provider '%s' is a failover group, benchmark its members instead", name)
+	}
+	endpoint, envMap, err := providerEndpoint(cfg, name)
+	if err != nil {
+		return bench.Target{}, err
+	}
+
+	target := bench.Target{Provider: name, Endpoint: endpoint, Model: model}
+	if target.Model == "" && envMap["ANTHROPIC_MODEL"] != nil {
+		target.Model = provider.ExpandValue(envMap["ANTHROPIC_MODEL"])
+	}
+	if target.Model == "" {
+		entry, _, err := listModels(endpoint, false)
+		if err != nil {
+			return bench.Target{}, fmt.Errorf("provider '%s' has no ANTHROPIC_MODEL, use --model: %w", name, err)
+		}
+		if target.Model = validate.SelectBestModel(entry.Models); target.Model == "" {
+			return bench.Target{}, fmt.Errorf("provider '%s' has no ANTHROPIC_MODEL, use --model", name)
+		}
+	}
+	return target, nil
+}
+
+// benchProviders returns the providers to benchmark: the given ones,
+// all non-group providers with --all, or the current provider.
+func benchProviders(cfg *config.Config, opts *BenchCommand) ([]string, error) {
+	if len(opts.Providers) > 0 {
+		return opts.Providers, nil
+	}
+	if opts.All {
+		var names []string
+		for _, name := range provider.ListProviders(cfg) {
+			if failoverMembers(cfg, name) == nil {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+	if name := provider.GetCurrentProvider(cfg); name != "" {
+		return []string{name}, nil
+	}
+	return nil, fmt.Errorf("no providers configured")
+}
+
+// runBench benchmarks providers one after another, so that they do not
+// compete for bandwidth, and reports the results in the given order.
+func runBench(cfg *config.Config, opts *BenchCommand) error {
+	if opts.Err != nil {
+		return fmt.Errorf("%v\nusage: ccc bench [provider...] [--all] [-n 10] [--concurrency 2] [--stream] [--prompt text] [--model model] [--format table|json]", opts.Err)
+	}
+	if opts.Format != bench.FormatTable && opts.Format != bench.FormatJSON {
+		return fmt.Errorf("unknown format '%s' (supported: %s, %s)", opts.Format, bench.FormatTable, bench.FormatJSON)
+	}
+	if opts.Requests < 1 || opts.Concurrency < 1 || opts.MaxTokens < 1 {
+		return fmt.Errorf("-n, --concurrency and --max-tokens must be at least 1")
+	}
+
+	names, err := benchProviders(cfg, opts)
+	if err != nil {
+		return err
+	}
+	targets := make([]bench.Target, 0, len(names))
+	for _, name := range names {
+		target, err := benchTarget(cfg, name, opts.Model)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	benchOpts := bench.Options{
+		Requests:    opts.Requests,
+		Concurrency: opts.Concurrency,
+		Stream:      opts.Stream,
+		Prompts:     opts.Prompts,
+		MaxTokens:   opts.MaxTokens,
+		Timeout:     opts.Timeout,
+	}
+	results := make([]*bench.Result, 0, len(targets))
+	failed := 0
+	for _, target := range targets {
+		fmt.Fprintf(os.Stderr, "Benchmarking %s (%s, %d requests)...\n", target.Provider, target.Model, opts.Requests)
+		result := bench.Run(ctx, target, benchOpts)
+		results = append(results, result)
+		failed += result.Errors
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if opts.Format == bench.FormatTable {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err := bench.WriteReport(os.Stdout, opts.Format, results); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return &ExitError{Code: 130}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d request(s) failed", failed)
+	}
+	return nil
+}
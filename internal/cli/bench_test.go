@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func TestParseBenchArgs(t *testing.T) {
+	opts := parseBenchArgs([]string{"kimi", "glm", "-n", "20", "--stream", "--prompt", "a", "--prompt", "b", "--format", "json"})
+	if opts.Err != nil {
+		t.Fatalf("unexpected error: %v", opts.Err)
+	}
+	if strings.Join(opts.Providers, ",") != "kimi,glm" || opts.Requests != 20 || !opts.Stream {
+		t.Errorf("opts = %+v", opts)
+	}
+	if strings.Join(opts.Prompts, ",") != "a,b" || opts.Format != "json" || opts.Concurrency != 2 {
+		t.Errorf("opts = %+v", opts)
+	}
+}
+
+func TestBenchTarget(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {"env": map[string]interface{}{
+				"ANTHROPIC_BASE_URL":   "https://api.moonshot.cn/anthropic",
+				"ANTHROPIC_AUTH_TOKEN": "sk-kimi",
+				"ANTHROPIC_MODEL":      "kimi-k2",
+			}},
+		},
+		Aliases: map[string]string{"k": "kimi"},
+	}
+
+	target, err := benchTarget(cfg, "k", "")
+	if err != nil {
+		t.Fatalf("benchTarget() error = %v", err)
+	}
+	if target.Provider != "kimi" || target.Model != "kimi-k2" {
+		t.Errorf("benchTarget(k) = %+v, want provider kimi with model kimi-k2", target)
+	}
+	if _, err := benchTarget(cfg, "kmi", ""); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("benchTarget(kmi) error = %v, want not found", err)
+	}
+}
@@ -4,12 +4,15 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/guyskk/ccc/internal/config"
 	"github.com/guyskk/ccc/internal/migration"
 	"github.com/guyskk/ccc/internal/provider"
+	"github.com/guyskk/ccc/internal/secret"
 	"github.com/guyskk/ccc/internal/validate"
 )
 
@@ -24,20 +27,81 @@ var BuildTime = "unknown"
 
 // Command represents a parsed CLI command.
 type Command struct {
-	Version      bool
-	Help         bool
-	Provider     string
-	ClaudeArgs   []string
-	Validate     bool
-	ValidateOpts *ValidateCommand
-	Patch        bool
-	PatchOpts    *PatchCommandOptions
+	Version        bool
+	Help           bool
+	Pick           bool
+	Provider       string
+	ClaudeArgs     []string
+	Validate       bool
+	ValidateOpts   *ValidateCommand
+	Patch          bool
+	PatchOpts      *PatchCommandOptions
+	Gateway        bool
+	GatewayOpts    *GatewayCommand
+	Exec           bool
+	ExecOpts       *ExecCommand
+	Env            bool
+	EnvOpts        *EnvCommand
+	Restore        bool
+	RestoreOpts    *RestoreCommand
+	ProviderCmd    bool
+	ProviderOpts   *ProviderCommand
+	Presets        bool
+	PresetsOpts    *PresetsCommand
+	Models         bool
+	ModelsOpts     *ModelsCommand
+	Bench          bool
+	BenchOpts      *BenchCommand
+	Completion     bool
+	CompletionOpts *CompletionCommand
+	List           bool
+	ListOpts       *ListCommand
+	Current        bool
+	CurrentOpts    *CurrentCommand
+	Trust          bool
+	TrustOpts      *TrustCommand
+	Complete       bool     // Hidden __complete entry point used by the completion scripts
+	CompleteArgs   []string // Words after "ccc", the last one being completed
+}
+
+// reservedNames lists the subcommands, which cannot be used as provider names.
+var reservedNames = []string{"validate", "patch", "env", "exec", "gateway", "restore", "provider", "presets", "models", "bench", "completion", "list", "current", "trust"}
+
+// isReservedName reports whether name is a subcommand.
+func isReservedName(name string) bool {
+	for _, reserved := range reservedNames {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitError reports that ccc should exit with the given status code
+// without printing an error message, e.g. to propagate a child's exit status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
 
 // ValidateCommand represents options for the validate command.
 type ValidateCommand struct {
 	Provider    string // Empty means current provider
 	ValidateAll bool
+	Format      string // text (default), json or junit
+	Deep        bool   // --deep flag, also check streaming, tool use, thinking and images
+
+	Timeout     time.Duration // --timeout flag, per request; zero means the default
+	Retries     int           // --retries flag, retries of failed requests with backoff
+	Concurrency int           // --concurrency flag, providers validated at once with --all
+
+	// TimeoutSet and RetriesSet report whether --timeout and --retries were
+	// given, in which case they override the provider's validate settings
+	TimeoutSet bool
+	RetriesSet bool
 }
 
 // PatchCommandOptions represents options for the patch command.
@@ -45,6 +109,18 @@ type PatchCommandOptions struct {
 	Reset bool // --reset flag, true means restore original claude
 }
 
+// CompletionCommand represents options for the completion command.
+type CompletionCommand struct {
+	Shell string // bash, zsh or fish; empty if missing or followed by extra arguments
+}
+
+// GatewayCommand represents options for the gateway command.
+type GatewayCommand struct {
+	Listen    string   // --listen address, empty means defaultGatewayListen
+	Token     string   // --token client credential, empty means generate one
+	Providers []string // Providers in failover order, or a single failover group
+}
+
 // Parse parses command-line arguments.
 func Parse(args []string) *Command {
 	cmd := &Command{}
@@ -57,12 +133,60 @@ func Parse(args []string) *Command {
 		cmd.Version = true
 	} else if firstArg == "--help" || firstArg == "-h" {
 		cmd.Help = true
+	} else if firstArg == "--pick" {
+		cmd.Pick = true
+		cmd.ClaudeArgs = args[1:]
 	} else if firstArg == "validate" {
 		cmd.Validate = true
 		cmd.ValidateOpts = parseValidateArgs(args[1:])
 	} else if firstArg == "patch" {
 		cmd.Patch = true
 		cmd.PatchOpts = parsePatchArgs(args[1:])
+	} else if firstArg == "env" {
+		cmd.Env = true
+		cmd.EnvOpts = parseEnvArgs(args[1:])
+	} else if firstArg == "exec" {
+		cmd.Exec = true
+		cmd.ExecOpts = parseExecArgs(args[1:])
+	} else if firstArg == "restore" {
+		cmd.Restore = true
+		cmd.RestoreOpts = parseRestoreArgs(args[1:])
+	} else if firstArg == "provider" {
+		cmd.ProviderCmd = true
+		cmd.ProviderOpts = parseProviderArgs(args[1:])
+	} else if firstArg == "presets" {
+		cmd.Presets = true
+		cmd.PresetsOpts = parsePresetsArgs(args[1:])
+	} else if firstArg == "models" {
+		cmd.Models = true
+		cmd.ModelsOpts = parseModelsArgs(args[1:])
+	} else if firstArg == "bench" {
+		cmd.Bench = true
+		cmd.BenchOpts = parseBenchArgs(args[1:])
+	} else if firstArg == "gateway" {
+		cmd.Gateway = true
+		cmd.GatewayOpts = parseGatewayArgs(args[1:])
+	} else if firstArg == "completion" {
+		cmd.Completion = true
+		cmd.CompletionOpts = &CompletionCommand{}
+		if len(args) == 2 {
+			cmd.CompletionOpts.Shell = args[1]
+		}
+	} else if firstArg == "list" {
+		cmd.List = true
+		cmd.ListOpts = parseListArgs(args[1:])
+	} else if firstArg == "current" {
+		cmd.Current = true
+		cmd.CurrentOpts = parseCurrentArgs(args[1:])
+	} else if firstArg == "trust" {
+		cmd.Trust = true
+		cmd.TrustOpts = &TrustCommand{}
+		if len(args) > 1 {
+			cmd.TrustOpts.Path = args[1]
+		}
+	} else if firstArg == "__complete" {
+		cmd.Complete = true
+		cmd.CompleteArgs = args[1:]
 	} else if !strings.HasPrefix(firstArg, "-") {
 		cmd.Provider = firstArg
 		if len(args) > 1 {
@@ -81,6 +205,11 @@ func parseValidateArgs(args []string) *ValidateCommand {
 	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
 	fs.Usage = func() {} // Suppress default usage output
 	all := fs.Bool("all", false, "validate all providers")
+	format := fs.String("format", "text", "output format")
+	deep := fs.Bool("deep", false, "check streaming, tool use, thinking and images")
+	timeout := fs.Duration("timeout", 0, "timeout per request")
+	retries := fs.Int("retries", 0, "retries of failed requests")
+	concurrency := fs.Int("concurrency", 4, "providers validated at once")
 
 	if err := fs.Parse(args); err != nil {
 		// On parse error, return options with defaults
@@ -88,6 +217,19 @@ func parseValidateArgs(args []string) *ValidateCommand {
 	}
 
 	opts.ValidateAll = *all
+	opts.Format = *format
+	opts.Deep = *deep
+	opts.Timeout = *timeout
+	opts.Retries = *retries
+	opts.Concurrency = *concurrency
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "timeout":
+			opts.TimeoutSet = true
+		case "retries":
+			opts.RetriesSet = true
+		}
+	})
 
 	// Get remaining arguments as positional args
 	remaining := fs.Args()
@@ -116,27 +258,375 @@ func parsePatchArgs(args []string) *PatchCommandOptions {
 	return opts
 }
 
+// parseGatewayArgs parses arguments for the gateway command.
+func parseGatewayArgs(args []string) *GatewayCommand {
+	opts := &GatewayCommand{}
+
+	fs := flag.NewFlagSet("gateway", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	listen := fs.String("listen", "", "address to listen on")
+	token := fs.String("token", "", "token clients must present")
+
+	if err := fs.Parse(args); err != nil {
+		// On parse error, return options with defaults
+		return opts
+	}
+
+	opts.Listen = *listen
+	opts.Token = *token
+	opts.Providers = fs.Args()
+
+	return opts
+}
+
+// ExecCommand represents options for the exec command.
+type ExecCommand struct {
+	Provider string   // Empty means current provider
+	Command  []string // Command and its arguments
+}
+
+// parseExecArgs parses arguments for the exec command:
+// [provider] [--] command [args...]
+func parseExecArgs(args []string) *ExecCommand {
+	opts := &ExecCommand{}
+	if len(args) > 0 && args[0] != "--" {
+		opts.Provider = args[0]
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	opts.Command = args
+	return opts
+}
+
+// EnvCommand represents options for the env command.
+type EnvCommand struct {
+	Provider string // Empty means current provider
+	Format   string // bash (default), zsh, fish, powershell, dotenv or json
+}
+
+// parseEnvArgs parses arguments for the env command.
+func parseEnvArgs(args []string) *EnvCommand {
+	opts := &EnvCommand{}
+
+	fs := flag.NewFlagSet("env", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	format := fs.String("format", "bash", "output format")
+
+	// Allow the provider before or after flags: ccc env kimi --format fish
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		opts.Provider = args[0]
+		args = args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		// On parse error, return options with defaults
+		opts.Format = "bash"
+		return opts
+	}
+
+	opts.Format = *format
+	if remaining := fs.Args(); len(remaining) > 0 && opts.Provider == "" {
+		opts.Provider = remaining[0]
+	}
+
+	return opts
+}
+
+// RestoreCommand represents options for the restore command.
+type RestoreCommand struct {
+	List bool   // --list flag, true means only list backups
+	ID   string // Backup ID, ID prefix or file name; empty means the newest backup
+}
+
+// parseRestoreArgs parses arguments for the restore command.
+func parseRestoreArgs(args []string) *RestoreCommand {
+	opts := &RestoreCommand{}
+
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	list := fs.Bool("list", false, "list backups")
+
+	if err := fs.Parse(args); err != nil {
+		// On parse error, only list backups rather than restoring one
+		opts.List = true
+		return opts
+	}
+
+	opts.List = *list
+	if remaining := fs.Args(); len(remaining) > 0 {
+		opts.ID = remaining[0]
+	}
+
+	return opts
+}
+
+// ProviderCommand represents options for the provider command.
+type ProviderCommand struct {
+	Action         string   // add, rm, mv, cp, set or get
+	Args           []string // Positional arguments of the action
+	BaseURL        string   // add: --base-url
+	Model          string   // add: --model
+	SmallFastModel string   // add: --small-fast-model
+	Token          string   // add: --token, prompted for when empty
+	Extends        string   // add: --extends
+	Preset         string   // add: --preset, fills in base URL, models and headers
+	JSON           bool     // set: --json, parse the value as JSON
+	Unset          bool     // set: --unset, remove the value
+	Err            error    // Flag parse error, reported instead of running
+}
+
+// parseProviderArgs parses arguments for the provider command.
+// Flags may appear anywhere after the action.
+func parseProviderArgs(args []string) *ProviderCommand {
+	opts := &ProviderCommand{}
+	if len(args) == 0 {
+		return opts
+	}
+	opts.Action = args[0]
+
+	fs := flag.NewFlagSet("provider", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.BaseURL, "base-url", "", "ANTHROPIC_BASE_URL")
+	fs.StringVar(&opts.Model, "model", "", "ANTHROPIC_MODEL")
+	fs.StringVar(&opts.SmallFastModel, "small-fast-model", "", "ANTHROPIC_SMALL_FAST_MODEL")
+	fs.StringVar(&opts.Token, "token", "", "ANTHROPIC_AUTH_TOKEN")
+	fs.StringVar(&opts.Extends, "extends", "", "provider to inherit from")
+	fs.StringVar(&opts.Preset, "preset", "", "preset to start from")
+	fs.BoolVar(&opts.JSON, "json", false, "parse the value as JSON")
+	fs.BoolVar(&opts.Unset, "unset", false, "remove the value")
+
+	opts.Args, opts.Err = parseInterspersed(fs, args[1:])
+	return opts
+}
+
+// PresetsCommand represents options for the presets command.
+type PresetsCommand struct {
+	Action string // Only "list" is supported; empty means list
+	JSON   bool   // --json flag, print presets as JSON
+	Err    error  // Flag parse error, reported instead of running
+}
+
+// parsePresetsArgs parses arguments for the presets command.
+func parsePresetsArgs(args []string) *PresetsCommand {
+	opts := &PresetsCommand{}
+
+	fs := flag.NewFlagSet("presets", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.JSON, "json", false, "print presets as JSON")
+
+	var positional []string
+	positional, opts.Err = parseInterspersed(fs, args)
+	if len(positional) > 0 {
+		opts.Action = positional[0]
+	}
+	return opts
+}
+
+// ListCommand represents options for the list command.
+type ListCommand struct {
+	JSON  bool  // --json flag, print providers as JSON
+	Quiet bool  // --quiet flag, print nothing, exit 1 if no providers are configured
+	Err   error // Flag parse error, reported instead of running
+}
+
+// parseListArgs parses arguments for the list command.
+func parseListArgs(args []string) *ListCommand {
+	opts := &ListCommand{}
+	opts.JSON, opts.Quiet, opts.Err = parseOutputFlags("list", args)
+	return opts
+}
+
+// CurrentCommand represents options for the current command.
+type CurrentCommand struct {
+	JSON  bool  // --json flag, print the provider as JSON
+	Quiet bool  // --quiet flag, print nothing, exit 1 if there is no current provider
+	Err   error // Flag parse error, reported instead of running
+}
+
+// parseCurrentArgs parses arguments for the current command.
+func parseCurrentArgs(args []string) *CurrentCommand {
+	opts := &CurrentCommand{}
+	opts.JSON, opts.Quiet, opts.Err = parseOutputFlags("current", args)
+	return opts
+}
+
+// parseOutputFlags parses the --json and --quiet flags of a command
+// that takes no arguments.
+func parseOutputFlags(name string, args []string) (jsonOutput, quiet bool, err error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&jsonOutput, "json", false, "print as JSON")
+	fs.BoolVar(&quiet, "quiet", false, "print nothing, report through the exit status")
+	fs.BoolVar(&quiet, "q", false, "shorthand for --quiet")
+
+	if err := fs.Parse(args); err != nil {
+		return false, false, err
+	}
+	if fs.NArg() > 0 {
+		return false, false, fmt.Errorf("unexpected argument '%s'", fs.Arg(0))
+	}
+	return jsonOutput, quiet, nil
+}
+
+// ModelsCommand represents options for the models command.
+type ModelsCommand struct {
+	Provider string // Empty means current provider
+	Set      bool   // --set flag, pick and save the provider's models
+	Refresh  bool   // --refresh flag, ignore the cached model list
+	Err      error  // Flag parse error, reported instead of running
+}
+
+// parseModelsArgs parses arguments for the models command.
+func parseModelsArgs(args []string) *ModelsCommand {
+	opts := &ModelsCommand{}
+
+	fs := flag.NewFlagSet("models", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.Set, "set", false, "pick and save the provider's models")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "ignore the cached model list")
+
+	var positional []string
+	positional, opts.Err = parseInterspersed(fs, args)
+	if len(positional) > 1 {
+		opts.Err = fmt.Errorf("too many arguments: %s", strings.Join(positional, " "))
+	} else if len(positional) == 1 {
+		opts.Provider = positional[0]
+	}
+	return opts
+}
+
+// BenchCommand represents options for the bench command.
+type BenchCommand struct {
+	Providers   []string      // Empty means current provider
+	All         bool          // --all flag, benchmark all providers
+	Requests    int           // -n flag, requests per provider
+	Concurrency int           // --concurrency flag, requests in flight per provider
+	Stream      bool          // --stream flag, stream responses to measure time to first token
+	Prompts     []string      // --prompt flags, used in turn
+	MaxTokens   int           // --max-tokens flag
+	Model       string        // --model flag, overrides the provider's ANTHROPIC_MODEL
+	Timeout     time.Duration // --timeout flag, per request
+	Format      string        // --format flag: table or json
+	Err         error         // Flag parse error, reported instead of running
+}
+
+// parseBenchArgs parses arguments for the bench command.
+func parseBenchArgs(args []string) *BenchCommand {
+	opts := &BenchCommand{}
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.Usage = func() {} // Suppress default usage output
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.All, "all", false, "benchmark all providers")
+	fs.IntVar(&opts.Requests, "n", 10, "requests per provider")
+	fs.IntVar(&opts.Concurrency, "concurrency", 2, "requests in flight per provider")
+	fs.BoolVar(&opts.Stream, "stream", false, "stream responses")
+	fs.Func("prompt", "prompt to send (repeatable)", func(s string) error {
+		opts.Prompts = append(opts.Prompts, s)
+		return nil
+	})
+	fs.IntVar(&opts.MaxTokens, "max-tokens", 128, "max_tokens per request")
+	fs.StringVar(&opts.Model, "model", "", "model to use")
+	fs.DurationVar(&opts.Timeout, "timeout", 60*time.Second, "timeout per request")
+	fs.StringVar(&opts.Format, "format", "table", "output format: table or json")
+
+	opts.Providers, opts.Err = parseInterspersed(fs, args)
+	return opts
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments. Arguments after "--" are always positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
 // ShowHelp displays usage information.
 func ShowHelp(cfg *config.Config, cfgErr error) {
 	help := `Usage: ccc [provider] [args...]
-       ccc validate [provider] [--all]
+       ccc --pick [args...]
+       ccc validate [--all] [--deep] [--format text|json|junit] [--timeout 8s] [--retries 0] [--concurrency 4] [provider]
        ccc patch [--reset]
+       ccc exec [provider] -- <command> [args...]
+       ccc env [provider] [--format bash|zsh|fish|powershell|dotenv|json]
+       ccc gateway [--listen addr] [--token token] <provider>...
+       ccc restore [--list] [<id>]
+       ccc provider add|rm|mv|cp|set|get [args...]
+       ccc presets list [--json]
+       ccc models [provider] [--set] [--refresh]
+       ccc bench [provider...] [--all] [-n 10] [--concurrency 2] [--stream] [--format table|json]
+       ccc completion bash|zsh|fish
+       ccc list [--json] [--quiet]
+       ccc current [--json] [--quiet]
+       ccc trust [path]
 
 Claude Code Configuration Switcher
 
 Commands:
-  ccc                    Use the current provider (or the first provider if none is set)
+  ccc                    Use the current provider (or the default provider if none is set)
   ccc <provider>         Switch to the specified provider and run Claude Code
+  ccc --pick             Pick a provider interactively and run Claude Code
   ccc validate           Validate the current provider configuration
   ccc validate <provider>         Validate a specific provider configuration
   ccc validate --all              Validate all provider configurations
+  ccc validate --all --retries 2  Retry network errors, HTTP 429 and 5xx with backoff
+  ccc validate --deep             Also check streaming, tool use, thinking and images
+  ccc validate --format json      Print results as JSON (or junit for CI test reporters)
   ccc patch               Replace claude command with ccc (requires sudo)
   ccc patch --reset       Restore original claude command (requires sudo)
+  ccc exec <provider> -- <cmd>    Run any command with the provider's environment
+  ccc env <provider>     Print export statements, e.g. eval "$(ccc env kimi)"
+  ccc gateway <group>     Run a local failover gateway for a failover group
+  ccc gateway <p1> <p2>   Run a local failover gateway for providers in order
+  ccc provider add <name> --base-url <url>  Add a provider (prompts for the token)
+  ccc provider add --preset <preset>  Add a provider from a preset
+  ccc provider rm|mv|cp   Remove, rename or copy a provider
+  ccc provider set <name> env.ANTHROPIC_MODEL <model>  Set a provider value
+  ccc provider get <name> [<path>]  Print a provider or one of its values
+  ccc models <provider>   List the provider's models (cached for 24h)
+  ccc models <provider> --set  Pick ANTHROPIC_MODEL and ANTHROPIC_SMALL_FAST_MODEL
+  ccc bench <p1> <p2>     Compare latency, time to first token (--stream) and tokens/s
+  ccc presets list        List provider presets (builtin and ~/.claude/ccc/presets/)
+  ccc restore --list      List backups of ccc.json and settings.json
+  ccc restore [<id>]      Restore a backup (default: the newest one)
+  ccc completion bash     Print a completion script, e.g. source <(ccc completion bash)
+  ccc list                List providers with base URL host, model and last validation
+  ccc current             Print the current provider, e.g. for a shell prompt
+  ccc trust               Use the nearest .ccc.json, after reviewing it
   ccc --help             Show this help message
   ccc --version          Show version information
 
+Project Config:
+  A .ccc.json in the working directory or any parent can pin a provider,
+  add providers and override claude_args for that project. It is ignored
+  until trusted with 'ccc trust', and again whenever it changes.
+
 Environment Variables:
   CCC_CONFIG_DIR         Override the configuration directory (default: ~/.claude/)
+  NO_COLOR               Disable colored output
 `
 	fmt.Print(help)
 
@@ -147,15 +637,31 @@ Environment Variables:
 		fmt.Printf("\nCurrent config: %s (%s)\n", configPath, errMsg)
 	} else {
 		fmt.Printf("\nCurrent config: %s\n", configPath)
+		if cfg != nil {
+			for _, source := range cfg.Sources {
+				if source != configPath {
+					fmt.Printf("Project config: %s\n", source)
+				}
+			}
+			if cfg.UntrustedProject != "" {
+				fmt.Printf("Project config: %s (ignored until trusted, see 'ccc trust')\n", cfg.UntrustedProject)
+			}
+		}
 
 		// Display provider list from config
 		if cfg != nil && len(cfg.Providers) > 0 {
 			fmt.Println("\nAvailable Providers:")
-			for name := range cfg.Providers {
+			for _, name := range cfg.ProviderNames() {
 				marker := ""
 				if name == cfg.CurrentProvider {
 					marker = " (current)"
 				}
+				if name == cfg.DefaultProvider {
+					marker += " (default)"
+				}
+				if _, err := config.ResolveProvider(cfg, name); err != nil {
+					marker += fmt.Sprintf(" (invalid: %v)", err)
+				}
 				fmt.Printf("  %s%s\n", name, marker)
 			}
 		}
@@ -175,6 +681,35 @@ func Run(cmd *Command) error {
 		return RunPatch(cmd.PatchOpts)
 	}
 
+	// Handle restore subcommand, which must work even with a broken config
+	if cmd.Restore {
+		return runRestore(cmd.RestoreOpts)
+	}
+
+	// Handle provider subcommand, which edits ~/.claude/ccc.json directly
+	// and can create it
+	if cmd.ProviderCmd {
+		return runProvider(cmd.ProviderOpts)
+	}
+
+	// Handle presets subcommand, which does not need ccc.json
+	if cmd.Presets {
+		return runPresets(os.Stdout, cmd.PresetsOpts)
+	}
+
+	// Handle trust subcommand, which edits ~/.claude/ccc.json directly
+	if cmd.Trust {
+		return runTrust(os.Stdout, cmd.TrustOpts)
+	}
+
+	// Handle shell completion, which must stay quiet with a broken config
+	if cmd.Completion {
+		return runCompletion(os.Stdout, cmd.CompletionOpts)
+	}
+	if cmd.Complete {
+		return runComplete(os.Stdout, cmd.CompleteArgs)
+	}
+
 	// Handle --version
 	if cmd.Version {
 		ShowVersion()
@@ -188,6 +723,15 @@ func Run(cmd *Command) error {
 		return nil
 	}
 
+	// Handle list and current, which prompts and status bars run often,
+	// so they never offer a migration
+	if cmd.List {
+		return runList(os.Stdout, cmd.ListOpts)
+	}
+	if cmd.Current {
+		return runCurrent(os.Stdout, cmd.CurrentOpts)
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -207,11 +751,36 @@ func Run(cmd *Command) error {
 			return err
 		}
 	}
+	warnUntrustedProject(os.Stderr, cfg)
 
 	if cmd.Validate {
 		return runValidate(cfg, cmd.ValidateOpts)
 	}
 
+	if cmd.Gateway {
+		return runGateway(cfg, cmd.GatewayOpts)
+	}
+
+	if cmd.Exec {
+		return runExec(cfg, cmd.ExecOpts)
+	}
+
+	if cmd.Env {
+		return runEnv(cfg, cmd.EnvOpts)
+	}
+
+	if cmd.Bench {
+		return runBench(cfg, cmd.BenchOpts)
+	}
+
+	if cmd.Models {
+		return runModels(cfg, cmd.ModelsOpts)
+	}
+
+	if cmd.Pick {
+		return runPick(cfg, cmd)
+	}
+
 	// Run claude with the provider (provider determination is inside runClaude)
 	return runClaude(cfg, cmd)
 }
@@ -224,6 +793,14 @@ func runValidate(cfg *config.Config, opts *ValidateCommand) error {
 	validateOpts := &validate.RunOptions{
 		Provider:    opts.Provider,
 		ValidateAll: opts.ValidateAll,
+		Format:      opts.Format,
+		Deep:        opts.Deep,
+		Timeout:     opts.Timeout,
+		Retries:     opts.Retries,
+		Concurrency: opts.Concurrency,
+		TimeoutSet:  opts.TimeoutSet,
+		RetriesSet:  opts.RetriesSet,
+		Report:      recordValidation,
 	}
 
 	return validate.Run(cfgAdapter, validateOpts)
@@ -238,8 +815,57 @@ func (a *configAdapter) Providers() map[string]map[string]interface{} {
 	return a.cfg.Providers
 }
 
+func (a *configAdapter) ProviderNames() []string {
+	return a.cfg.ProviderNames()
+}
+
 func (a *configAdapter) CurrentProvider() string {
-	return a.cfg.CurrentProvider
+	return provider.GetCurrentProvider(a.cfg)
+}
+
+func (a *configAdapter) ResolveAlias(name string) string {
+	return config.ResolveAlias(a.cfg, name)
+}
+
+func (a *configAdapter) CheckAliases(name string) []string {
+	return config.CheckAliases(a.cfg, name, reservedNames)
+}
+
+// IsFailoverGroup applies the extends chain without resolving secrets, so
+// validating a failover group never runs the cmd: references of its members.
+func (a *configAdapter) IsFailoverGroup(name string) bool {
+	resolved, err := config.ResolveProvider(a.cfg, name)
+	if err != nil {
+		return false
+	}
+	_, isGroup := resolved["failover"]
+	return isGroup
+}
+
+// ResolveProvider applies the extends chain and resolves secret references
+// in the provider env. Secrets are only resolved for the provider being validated.
+// An apiKeyHelper in the base settings applies unless the provider sets its own.
+func (a *configAdapter) ResolveProvider(name string) (map[string]interface{}, error) {
+	resolved, err := config.ResolveProvider(a.cfg, name)
+	if err != nil {
+		return nil, err
+	}
+	if helper := config.GetAPIKeyHelper(a.cfg.Settings); helper != "" && config.GetAPIKeyHelper(resolved) == "" {
+		resolved["apiKeyHelper"] = helper
+	}
+	env, err := provider.ResolveSecrets(name, config.GetEnv(resolved))
+	if err != nil {
+		return nil, err
+	}
+	if env != nil {
+		for k, v := range env {
+			if s, ok := v.(secret.Value); ok {
+				env[k] = string(s)
+			}
+		}
+		resolved["env"] = env
+	}
+	return resolved, nil
 }
 
 // Execute is the main entry point for the CLI.
@@ -2,9 +2,13 @@ package cli
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/validate"
 )
 
 func setupTestDir(t *testing.T) func() {
@@ -188,48 +192,74 @@ func TestDetermineProvider(t *testing.T) {
 	cfg := &config.Config{
 		CurrentProvider: "kimi",
 		Providers: map[string]map[string]interface{}{
-			"kimi": {},
-			"glm":  {},
+			"kimi":     {"aliases": []interface{}{"k", "moonshot"}},
+			"glm":      {},
+			"minimax":  {},
+			"minimax2": {},
 		},
+		Aliases: map[string]string{"zhipu": "glm", "glm4": "glm"},
 	}
 
 	tests := []struct {
-		name string
-		cmd  *Command
-		want string
+		name    string
+		cmd     *Command
+		strict  string
+		want    string
+		wantErr string
 	}{
-		{
-			name: "valid provider specified",
-			cmd: &Command{
-				Provider: "glm",
-			},
-			want: "glm",
-		},
-		{
-			name: "invalid provider, use current",
-			cmd: &Command{
-				Provider: "unknown",
-			},
-			want: "kimi",
-		},
-		{
-			name: "no provider specified, use current",
-			cmd: &Command{
-				Provider: "",
-			},
-			want: "kimi",
-		},
+		{name: "valid provider specified", cmd: &Command{Provider: "glm"}, want: "glm"},
+		{name: "invalid provider, use current", cmd: &Command{Provider: "unknown"}, want: "kimi"},
+		{name: "no provider specified, use current", cmd: &Command{Provider: ""}, want: "kimi"},
+		{name: "unique prefix of a name and an alias", cmd: &Command{Provider: "gl"}, want: "glm"},
+		{name: "unique prefix ignores case", cmd: &Command{Provider: "GL"}, want: "glm"},
+		{name: "alias", cmd: &Command{Provider: "k"}, want: "kimi"},
+		{name: "top-level alias", cmd: &Command{Provider: "zhipu"}, want: "glm"},
+		{name: "unique alias prefix", cmd: &Command{Provider: "moon"}, want: "kimi"},
+		{name: "unique top-level alias prefix", cmd: &Command{Provider: "zh"}, want: "glm"},
+		{name: "ambiguous prefix, use current", cmd: &Command{Provider: "mini"}, want: "kimi"},
+		{name: "typo, use current", cmd: &Command{Provider: "kmi"}, want: "kimi"},
+		{name: "strict typo", cmd: &Command{Provider: "kmi"}, strict: "1", wantErr: "unknown provider 'kmi' (did you mean 'kimi'?)"},
+		{name: "strict unknown", cmd: &Command{Provider: "unknown"}, strict: "true", wantErr: "unknown provider 'unknown'"},
+		{name: "strict unique prefix", cmd: &Command{Provider: "gl"}, strict: "1", want: "glm"},
+		{name: "strict disabled", cmd: &Command{Provider: "kmi"}, strict: "0", want: "kimi"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := determineProvider(tt.cmd, cfg)
+			t.Setenv("CCC_STRICT", tt.strict)
+			got, err := determineProvider(tt.cmd, cfg)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("determineProvider() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("determineProvider() error = %v", err)
+			}
 			if got != tt.want {
 				t.Errorf("determineProvider() = %q, want %q", got, tt.want)
 			}
 		})
 	}
 
+	t.Run("strict_provider config", func(t *testing.T) {
+		t.Setenv("CCC_STRICT", "")
+		cfg := &config.Config{
+			CurrentProvider: "kimi",
+			StrictProvider:  true,
+			Providers:       map[string]map[string]interface{}{"kimi": {}},
+		}
+		if _, err := determineProvider(&Command{Provider: "kmi"}, cfg); err == nil {
+			t.Error("expected an error for an unknown provider with strict_provider")
+		}
+
+		t.Setenv("CCC_STRICT", "0")
+		if got, err := determineProvider(&Command{Provider: "kmi"}, cfg); err != nil || got != "kimi" {
+			t.Errorf("determineProvider() with CCC_STRICT=0 = %q, %v, want kimi", got, err)
+		}
+	})
+
 	// Separate test for "no current" case with different cfg
 	t.Run("no provider specified, no current, use first", func(t *testing.T) {
 		cfg := &config.Config{
@@ -240,25 +270,50 @@ func TestDetermineProvider(t *testing.T) {
 			},
 		}
 		cmd := &Command{Provider: ""}
-		got := determineProvider(cmd, cfg)
-		// Since map iteration order is random, just check it's one of the valid providers
-		if got != "kimi" && got != "glm" {
-			t.Errorf("determineProvider() = %q, want kimi or glm", got)
+		got, err := determineProvider(cmd, cfg)
+		if err != nil || got != "glm" {
+			t.Errorf("determineProvider() = %q, %v, want glm", got, err)
+		}
+
+		cfg.DefaultProvider = "kimi"
+		if got, err := determineProvider(cmd, cfg); err != nil || got != "kimi" {
+			t.Errorf("determineProvider() with default_provider = %q, %v, want kimi", got, err)
 		}
 	})
 
-	t.Run("invalid provider and no current", func(t *testing.T) {
+	t.Run("stale current_provider, use default", func(t *testing.T) {
+		t.Setenv("CCC_STRICT", "")
 		cfg := &config.Config{
-			CurrentProvider: "",
+			CurrentProvider: "removed",
+			DefaultProvider: "kimi",
 			Providers: map[string]map[string]interface{}{
+				"glm":  {},
 				"kimi": {},
 			},
 		}
+		if got, err := determineProvider(&Command{}, cfg); err != nil || got != "kimi" {
+			t.Errorf("determineProvider() = %q, %v, want kimi", got, err)
+		}
+		if got, err := determineProvider(&Command{Provider: "unknown"}, cfg); err != nil || got != "kimi" {
+			t.Errorf("determineProvider(unknown) = %q, %v, want kimi", got, err)
+		}
+
+		cfg.CurrentProvider = ""
+		if got, err := determineProvider(&Command{Provider: "unknown"}, cfg); err != nil || got != "kimi" {
+			t.Errorf("determineProvider(unknown) without current = %q, %v, want kimi", got, err)
+		}
+	})
+
+	t.Run("invalid provider and no providers", func(t *testing.T) {
+		t.Setenv("CCC_STRICT", "")
+		cfg := &config.Config{
+			CurrentProvider: "removed",
+			Providers:       map[string]map[string]interface{}{},
+		}
 
 		cmd := &Command{Provider: "unknown"}
-		got := determineProvider(cmd, cfg)
-		if got != "" {
-			t.Errorf("determineProvider() = %q, want empty string", got)
+		if _, err := determineProvider(cmd, cfg); err == nil || !strings.Contains(err.Error(), "unknown provider") {
+			t.Errorf("determineProvider() error = %v, want unknown provider", err)
 		}
 	})
 
@@ -269,13 +324,88 @@ func TestDetermineProvider(t *testing.T) {
 		}
 
 		cmd := &Command{Provider: ""}
-		got := determineProvider(cmd, cfg)
-		if got != "" {
-			t.Errorf("determineProvider() = %q, want empty string", got)
+		got, err := determineProvider(cmd, cfg)
+		if err != nil || got != "" {
+			t.Errorf("determineProvider() = %q, %v, want empty string", got, err)
 		}
 	})
 }
 
+func TestConfigAdapterCurrentProvider(t *testing.T) {
+	cfg := &config.Config{
+		CurrentProvider: "removed",
+		DefaultProvider: "kimi",
+		Providers:       map[string]map[string]interface{}{"glm": {}, "kimi": {}},
+	}
+	// 'ccc validate' without a provider validates what 'ccc' would run
+	if got := (&configAdapter{cfg: cfg}).CurrentProvider(); got != "kimi" {
+		t.Errorf("CurrentProvider() = %q, want kimi", got)
+	}
+}
+
+func TestValidateFailoverMemberSecrets(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {"env": map[string]interface{}{
+				"ANTHROPIC_BASE_URL":   "https://api.moonshot.cn/anthropic",
+				"ANTHROPIC_AUTH_TOKEN": "cmd:touch " + marker,
+			}},
+			"auto":   {"failover": []interface{}{"kimi"}},
+			"nested": {"failover": []interface{}{"auto"}},
+		},
+	}
+	adapter := &configAdapter{cfg: cfg}
+
+	if result := validate.ValidateProvider(adapter, "auto"); !result.Valid {
+		t.Errorf("ValidateProvider(auto) errors = %q", result.Errors)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("validating a failover group ran a member's cmd: secret")
+	}
+	result := validate.ValidateProvider(adapter, "nested")
+	if !containsString(result.Errors, "Failover member 'auto' is itself a failover group") {
+		t.Errorf("ValidateProvider(nested) errors = %q, want nested group error", result.Errors)
+	}
+}
+
+func TestValidateAliases(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {"aliases": []interface{}{"k", "patch"}},
+			"glm":  {"aliases": []interface{}{"kimi"}},
+			"list": {},
+		},
+	}
+	adapter := &configAdapter{cfg: cfg}
+
+	result := validate.ValidateProvider(adapter, "kimi")
+	if !containsString(result.Errors, "alias 'patch' collides with the 'patch' command") {
+		t.Errorf("ValidateProvider(kimi) errors = %q, want the reserved name collision", result.Errors)
+	}
+	result = validate.ValidateProvider(adapter, "glm")
+	if !containsString(result.Errors, "alias 'kimi' collides with provider 'kimi'") {
+		t.Errorf("ValidateProvider(glm) errors = %q, want the provider name collision", result.Errors)
+	}
+	// Providers named like a later subcommand are shadowed by it
+	result = validate.ValidateProvider(adapter, "list")
+	if result.Valid || len(result.Errors) == 0 || !strings.Contains(result.Errors[0], "collides with the 'list' command") {
+		t.Errorf("ValidateProvider(list) errors = %q, want the subcommand collision", result.Errors)
+	}
+
+	// validate.Run resolves the alias and reports the provider itself
+	err := validate.Run(adapter, &validate.RunOptions{Provider: "k", Format: validate.FormatJSON})
+	if err == nil || err.Error() != "provider 'kimi' is invalid" {
+		t.Errorf("validate.Run(k) error = %v, want provider 'kimi' is invalid", err)
+	}
+}
+
 func TestRun(t *testing.T) {
 	t.Run("--version", func(t *testing.T) {
 		cmd := &Command{Version: true}
@@ -301,6 +431,28 @@ func TestConstants(t *testing.T) {
 	}
 }
 
+func TestParseValidateFormat(t *testing.T) {
+	if got := parseValidateArgs([]string{"--all"}).Format; got != "text" {
+		t.Errorf("default Format = %q, want text", got)
+	}
+	opts := parseValidateArgs([]string{"--format", "junit", "--all"})
+	if opts.Format != "junit" || !opts.ValidateAll {
+		t.Errorf("parseValidateArgs() = %+v, want junit format for all providers", opts)
+	}
+}
+
+func TestParseValidateRequestFlags(t *testing.T) {
+	opts := parseValidateArgs([]string{"kimi"})
+	if opts.TimeoutSet || opts.RetriesSet {
+		t.Errorf("parseValidateArgs(kimi) = %+v, want no explicit flags", opts)
+	}
+	// An explicit --retries 0 must still override the provider settings
+	opts = parseValidateArgs([]string{"--timeout", "5s", "--retries", "0", "kimi"})
+	if !opts.TimeoutSet || !opts.RetriesSet || opts.Timeout != 5*time.Second || opts.Retries != 0 {
+		t.Errorf("parseValidateArgs() = %+v, want explicit timeout and retries", opts)
+	}
+}
+
 func TestParseValidateArgs(t *testing.T) {
 	tests := []struct {
 		name            string
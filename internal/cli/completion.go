@@ -0,0 +1,261 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/preset"
+	"github.com/guyskk/ccc/internal/provider"
+)
+
+// completionShells lists the shells supported by 'ccc completion'.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionScripts are the scripts printed by 'ccc completion <shell>'.
+// They call 'ccc __complete <words...>' with the words after "ccc", the
+// last one being the word under the cursor, and offer its output lines.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for ccc, load with: source <(ccc completion bash)
+_ccc() {
+    local IFS=$'\n'
+    COMPREPLY=($(ccc __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _ccc ccc
+`,
+	"zsh": `#compdef ccc
+# zsh completion for ccc, load with: source <(ccc completion zsh)
+_ccc() {
+    local -a candidates
+    candidates=(${(f)"$(ccc __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _ccc ccc
+`,
+	"fish": `# fish completion for ccc, load with: ccc completion fish | source
+function __ccc_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    ccc __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c ccc -f -a '(__ccc_complete)'
+`,
+}
+
+// runCompletion prints the completion script of a shell.
+func runCompletion(w io.Writer, opts *CompletionCommand) error {
+	script, ok := completionScripts[opts.Shell]
+	if !ok {
+		return fmt.Errorf("usage: ccc completion %s", strings.Join(completionShells, "|"))
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+// claudeFlags are the common Claude Code flags completed after a provider.
+var claudeFlags = []string{
+	"--add-dir", "--allowedTools", "--append-system-prompt", "--continue", "--debug",
+	"--dangerously-skip-permissions", "--disallowedTools", "--ide", "--mcp-config",
+	"--model", "--output-format", "--permission-mode", "--print", "--resume",
+	"--settings", "--verbose",
+}
+
+// claudeFlagValues are the values completed after Claude Code flags.
+var claudeFlagValues = map[string][]string{
+	"--model":           {"sonnet", "opus", "haiku"},
+	"--output-format":   {"text", "json", "stream-json"},
+	"--permission-mode": {"default", "acceptEdits", "plan", "bypassPermissions"},
+}
+
+// completionSpec describes how to complete the arguments of a subcommand.
+type completionSpec struct {
+	flags     []string                                 // Flags of the subcommand
+	values    map[string]func(*config.Config) []string // Candidates for flags that take a value
+	words     []string                                 // Candidates for the first argument
+	providers int                                      // Number of provider arguments, -1 for any
+	aliases   bool                                     // Provider arguments may be aliases
+}
+
+// fixedValues returns a candidates function for a fixed list of values.
+func fixedValues(values ...string) func(*config.Config) []string {
+	return func(*config.Config) []string { return values }
+}
+
+// noValues completes flags whose value cannot be guessed.
+var noValues = fixedValues()
+
+// completionSpecs describe the subcommands, keyed by name.
+var completionSpecs = map[string]completionSpec{
+	"validate": {
+		flags: []string{"--all", "--deep", "--format", "--timeout", "--retries", "--concurrency"},
+		values: map[string]func(*config.Config) []string{
+			"--format": fixedValues("text", "json", "junit"), "--timeout": noValues,
+			"--retries": noValues, "--concurrency": noValues,
+		},
+		providers: 1,
+		aliases:   true,
+	},
+	"patch": {flags: []string{"--reset"}},
+	"env": {
+		flags:     []string{"--format"},
+		values:    map[string]func(*config.Config) []string{"--format": fixedValues(envFormats...)},
+		providers: 1,
+		aliases:   true,
+	},
+	"exec": {providers: 1, aliases: true},
+	"gateway": {
+		flags:     []string{"--listen", "--token"},
+		values:    map[string]func(*config.Config) []string{"--listen": noValues, "--token": noValues},
+		providers: -1,
+	},
+	"restore": {flags: []string{"--list"}},
+	"provider": {
+		flags: []string{"--base-url", "--model", "--small-fast-model", "--token", "--extends", "--preset", "--json", "--unset"},
+		values: map[string]func(*config.Config) []string{
+			"--base-url": noValues, "--model": noValues, "--small-fast-model": noValues, "--token": noValues,
+			"--extends": completeProviders, "--preset": completePresets,
+		},
+		words: []string{"add", "rm", "mv", "cp", "set", "get"},
+	},
+	"presets": {flags: []string{"--json"}, words: []string{"list"}},
+	"models":  {flags: []string{"--set", "--refresh"}, providers: 1, aliases: true},
+	"bench": {
+		flags: []string{"--all", "-n", "--concurrency", "--stream", "--prompt", "--max-tokens", "--model", "--timeout", "--format"},
+		values: map[string]func(*config.Config) []string{
+			"-n": noValues, "--concurrency": noValues, "--prompt": noValues, "--max-tokens": noValues,
+			"--model": noValues, "--timeout": noValues, "--format": fixedValues("table", "json"),
+		},
+		providers: -1,
+		aliases:   true,
+	},
+	"completion": {words: completionShells},
+	"list":       {flags: []string{"--json", "--quiet"}},
+	"current":    {flags: []string{"--json", "--quiet"}},
+	"trust":      {},
+}
+
+// completeProviders returns the provider names, in declaration order.
+func completeProviders(cfg *config.Config) []string {
+	return provider.ListProviders(cfg)
+}
+
+// completeProvidersAndAliases returns the provider names, then their aliases.
+func completeProvidersAndAliases(cfg *config.Config) []string {
+	names := completeProviders(cfg)
+	if cfg != nil {
+		names = append(names, config.AllAliases(cfg)...)
+	}
+	return names
+}
+
+// completePresets returns the preset names.
+func completePresets(*config.Config) []string {
+	presets, err := preset.Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(presets))
+	for i, p := range presets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// complete returns the candidates for the last of args, the word under the
+// cursor, given the words before it. cfg may be nil if ccc.json cannot be loaded.
+func complete(cfg *config.Config, args []string) []string {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	cur, prev := args[len(args)-1], args[:len(args)-1]
+
+	if len(prev) == 0 {
+		if strings.HasPrefix(cur, "-") {
+			// Flags other than ccc's own run the current provider with them
+			return filterPrefix(append([]string{"--pick", "--help", "--version"}, claudeFlags...), cur)
+		}
+		candidates := append([]string{}, reservedNames...)
+		candidates = append(candidates, completeProvidersAndAliases(cfg)...)
+		return filterPrefix(candidates, cur)
+	}
+
+	spec, isSubcommand := completionSpecs[prev[0]]
+	if !isSubcommand {
+		// A provider or --pick: the rest is passed to Claude Code
+		if values, ok := claudeFlagValues[prev[len(prev)-1]]; ok {
+			return filterPrefix(values, cur)
+		}
+		if strings.HasPrefix(cur, "-") {
+			return filterPrefix(claudeFlags, cur)
+		}
+		return nil
+	}
+
+	if values, ok := spec.values[prev[len(prev)-1]]; ok && len(prev) > 1 {
+		return filterPrefix(values(cfg), cur)
+	}
+	if strings.HasPrefix(cur, "-") {
+		return filterPrefix(spec.flags, cur)
+	}
+
+	// Count the positional arguments before the cursor
+	var positional []string
+	for i := 1; i < len(prev); i++ {
+		arg := prev[i]
+		if arg == "--" {
+			return nil // The command of 'ccc exec', completed by the shell
+		}
+		if strings.HasPrefix(arg, "-") {
+			if _, takesValue := spec.values[arg]; takesValue {
+				i++
+			}
+			continue
+		}
+		positional = append(positional, arg)
+	}
+
+	switch {
+	case len(spec.words) > 0 && len(positional) == 0:
+		return filterPrefix(spec.words, cur)
+	case prev[0] == "provider":
+		// Every action but add takes an existing provider or alias first
+		if positional[0] != "add" && len(positional) == 1 {
+			return filterPrefix(completeProvidersAndAliases(cfg), cur)
+		}
+		return nil
+	case spec.providers < 0 || len(positional) < spec.providers:
+		if spec.aliases {
+			return filterPrefix(completeProvidersAndAliases(cfg), cur)
+		}
+		return filterPrefix(completeProviders(cfg), cur)
+	}
+	return nil
+}
+
+// filterPrefix returns the candidates starting with prefix.
+func filterPrefix(candidates []string, prefix string) []string {
+	var result []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// runComplete prints the candidates for 'ccc __complete', one per line.
+// Configuration errors are ignored, completion then offers no providers.
+func runComplete(w io.Writer, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = nil
+	}
+	for _, candidate := range complete(cfg, args) {
+		fmt.Fprintln(w, candidate)
+	}
+	return nil
+}
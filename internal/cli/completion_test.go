@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func TestParseCompletion(t *testing.T) {
+	cmd := Parse([]string{"completion", "zsh"})
+	if !cmd.Completion || cmd.CompletionOpts.Shell != "zsh" {
+		t.Errorf("Parse(completion zsh) = %+v", cmd)
+	}
+	if cmd := Parse([]string{"completion", "zsh", "extra"}); cmd.CompletionOpts.Shell != "" {
+		t.Errorf("extra arguments should leave Shell empty, got %q", cmd.CompletionOpts.Shell)
+	}
+	cmd = Parse([]string{"__complete", "validate", ""})
+	if !cmd.Complete || strings.Join(cmd.CompleteArgs, ",") != "validate," {
+		t.Errorf("Parse(__complete) = %+v", cmd)
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		var buf bytes.Buffer
+		if err := runCompletion(&buf, &CompletionCommand{Shell: shell}); err != nil {
+			t.Fatalf("runCompletion(%s) error = %v", shell, err)
+		}
+		if !strings.Contains(buf.String(), "ccc __complete") {
+			t.Errorf("%s script does not call ccc __complete:\n%s", shell, buf.String())
+		}
+	}
+	if err := runCompletion(&bytes.Buffer{}, &CompletionCommand{Shell: "tcsh"}); err == nil || !strings.Contains(err.Error(), "bash|zsh|fish") {
+		t.Errorf("runCompletion(tcsh) error = %v, want usage", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {"aliases": []interface{}{"k"}}, "glm": {}, "kimi-turbo": {},
+		},
+		Aliases: map[string]string{"zhipu": "glm", "gone": "deleted"},
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "validate patch env exec gateway restore provider presets models bench completion list current trust glm kimi kimi-turbo k zhipu"},
+		{[]string{"ki"}, "kimi kimi-turbo"},
+		{[]string{"z"}, "zhipu"},
+		{[]string{"--p"}, "--pick --permission-mode --print"},
+		{[]string{"kimi", "--perm"}, "--permission-mode"},
+		{[]string{"kimi", "--permission-mode", "p"}, "plan"},
+		{[]string{"kimi", "hello"}, ""},
+		{[]string{"--pick", "--con"}, "--continue"},
+		{[]string{"validate", "g"}, "glm"},
+		{[]string{"validate", "glm", ""}, ""},
+		{[]string{"validate", "z"}, "zhipu"},
+		{[]string{"validate", "--format", ""}, "text json junit"},
+		{[]string{"validate", "--format", "json", "k"}, "kimi kimi-turbo k"},
+		{[]string{"validate", "--d"}, "--deep"},
+		{[]string{"env", "kimi", "--format", "f"}, "fish"},
+		{[]string{"exec", "kimi", "--", ""}, ""},
+		{[]string{"bench", "kimi", "g"}, "glm"},
+		{[]string{"bench", "-n", "5", "g"}, "glm"},
+		{[]string{"bench", "z"}, "zhipu"},
+		{[]string{"env", "z"}, "zhipu"},
+		{[]string{"exec", "z"}, "zhipu"},
+		{[]string{"models", "z"}, "zhipu"},
+		{[]string{"provider", ""}, "add rm mv cp set get"},
+		{[]string{"provider", "rm", "g"}, "glm"},
+		{[]string{"provider", "rm", "z"}, "zhipu"},
+		{[]string{"provider", "get", "z"}, "zhipu"},
+		{[]string{"provider", "set", "k"}, "kimi kimi-turbo k"},
+		{[]string{"provider", "set", "kimi", "z"}, ""},
+		{[]string{"provider", "add", ""}, ""},
+		{[]string{"provider", "add", "x", "--preset", "glm"}, "glm glm-intl"},
+		{[]string{"provider", "add", "x", "--extends", "g"}, "glm"},
+		{[]string{"presets", ""}, "list"},
+		{[]string{"completion", ""}, "bash zsh fish"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(complete(cfg, tt.args), " ")
+		if got != tt.want {
+			t.Errorf("complete(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	// Without a config, providers are simply not offered
+	if got := complete(nil, []string{"validate", ""}); len(got) != 0 {
+		t.Errorf("complete(nil, validate) = %q, want none", got)
+	}
+}
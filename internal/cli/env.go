@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/prettyjson"
+	"github.com/guyskk/ccc/internal/provider"
+)
+
+// envFormats lists the output formats supported by the env command.
+var envFormats = []string{"bash", "zsh", "fish", "powershell", "dotenv", "json"}
+
+// runEnv prints the provider env as statements for the requested shell,
+// e.g. for use with: eval "$(ccc env kimi)"
+func runEnv(cfg *config.Config, opts *EnvCommand) error {
+	providerName := opts.Provider
+	if providerName == "" {
+		providerName = provider.GetCurrentProvider(cfg)
+		if providerName == "" {
+			return fmt.Errorf("no providers configured")
+		}
+	}
+	providerName, err := provider.Resolve(cfg, providerName)
+	if err != nil {
+		return err
+	}
+	if failoverMembers(cfg, providerName) != nil {
+		return fmt.Errorf("provider '%s' is a failover group, run 'ccc gateway %s' and point clients at it instead", providerName, providerName)
+	}
+
+	envMap, err := provider.ProviderEnv(cfg, providerName)
+	if err != nil {
+		return err
+	}
+	pairs := provider.EnvMapToPairs(envMap)
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+	return writeEnv(os.Stdout, opts.Format, pairs, unsetKeys(os.Environ(), pairs))
+}
+
+// unsetKeys returns the sorted ANTHROPIC_*/CLAUDE_* keys of the inherited
+// environment that the provider does not set, i.e. the keys runClaude strips.
+func unsetKeys(inherited []string, pairs []provider.EnvPair) []string {
+	defined := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		defined[pair.Key] = true
+	}
+
+	var keys []string
+	for _, kv := range inherited {
+		key, _, _ := strings.Cut(kv, "=")
+		if isStrippedEnvKey(key) && !defined[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeEnv writes set and unset statements in the given format.
+func writeEnv(w io.Writer, format string, pairs []provider.EnvPair, unset []string) error {
+	switch format {
+	case "", "bash", "zsh", "sh":
+		for _, key := range unset {
+			fmt.Fprintf(w, "unset %s\n", key)
+		}
+		for _, pair := range pairs {
+			fmt.Fprintf(w, "export %s=%s\n", pair.Key, quotePOSIX(pair.Value))
+		}
+	case "fish":
+		for _, key := range unset {
+			fmt.Fprintf(w, "set -e %s\n", key)
+		}
+		for _, pair := range pairs {
+			fmt.Fprintf(w, "set -gx %s %s\n", pair.Key, quoteFish(pair.Value))
+		}
+	case "powershell", "pwsh":
+		for _, key := range unset {
+			fmt.Fprintf(w, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", key)
+		}
+		for _, pair := range pairs {
+			fmt.Fprintf(w, "$Env:%s = %s\n", pair.Key, quotePowerShell(pair.Value))
+		}
+	case "dotenv":
+		// dotenv has no way to unset a variable
+		for _, pair := range pairs {
+			fmt.Fprintf(w, "%s=%s\n", pair.Key, quoteDotenv(pair.Value))
+		}
+	case "json":
+		// Keys to unset are reported as null
+		env := make(map[string]interface{}, len(pairs)+len(unset))
+		for _, key := range unset {
+			env[key] = nil
+		}
+		for _, pair := range pairs {
+			env[pair.Key] = pair.Value
+		}
+		data, err := prettyjson.Marshal(env)
+		if err != nil {
+			return fmt.Errorf("failed to marshal env: %w", err)
+		}
+		fmt.Fprintf(w, "%s\n", data)
+	default:
+		return fmt.Errorf("unknown format '%s' (supported: %s)", format, strings.Join(envFormats, ", "))
+	}
+	return nil
+}
+
+// quotePOSIX quotes a value for bash/zsh/sh using single quotes.
+func quotePOSIX(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// quoteFish quotes a value for fish, where \ and ' are escaped inside single quotes.
+func quoteFish(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", `\'`) + "'"
+}
+
+// quotePowerShell quotes a value for PowerShell, where ' is doubled inside single quotes.
+func quotePowerShell(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// quoteDotenv quotes a value for .env files. Single quotes keep the value
+// literal; values containing a single quote fall back to escaped double quotes.
+func quoteDotenv(value string) string {
+	if !strings.ContainsAny(value, "'\n") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/provider"
+)
+
+func TestParseEnvArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantProvider string
+		wantFormat   string
+	}{
+		{"defaults", []string{}, "", "bash"},
+		{"provider only", []string{"kimi"}, "kimi", "bash"},
+		{"provider then format", []string{"kimi", "--format", "fish"}, "kimi", "fish"},
+		{"format then provider", []string{"--format", "json", "glm"}, "glm", "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Parse(append([]string{"env"}, tt.args...))
+			if !cmd.Env {
+				t.Fatal("Env = false, want true")
+			}
+			if cmd.EnvOpts.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, want %q", cmd.EnvOpts.Provider, tt.wantProvider)
+			}
+			if cmd.EnvOpts.Format != tt.wantFormat {
+				t.Errorf("Format = %q, want %q", cmd.EnvOpts.Format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestUnsetKeys(t *testing.T) {
+	inherited := []string{"PATH=/bin", "ANTHROPIC_MODEL=old", "CLAUDE_CONFIG_DIR=/x", "ANTHROPIC_BASE_URL=old"}
+	pairs := []provider.EnvPair{{Key: "ANTHROPIC_BASE_URL", Value: "new"}}
+
+	got := unsetKeys(inherited, pairs)
+	want := []string{"ANTHROPIC_MODEL", "CLAUDE_CONFIG_DIR"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("unsetKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteEnv(t *testing.T) {
+	pairs := []provider.EnvPair{
+		{Key: "ANTHROPIC_AUTH_TOKEN", Value: `sk-it's-$secret`},
+		{Key: "ANTHROPIC_BASE_URL", Value: "https://api.moonshot.cn/anthropic"},
+	}
+	unset := []string{"ANTHROPIC_MODEL"}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"bash", []string{
+			"unset ANTHROPIC_MODEL",
+			`export ANTHROPIC_AUTH_TOKEN='sk-it'\''s-$secret'`,
+			"export ANTHROPIC_BASE_URL='https://api.moonshot.cn/anthropic'",
+		}},
+		{"fish", []string{
+			"set -e ANTHROPIC_MODEL",
+			`set -gx ANTHROPIC_AUTH_TOKEN 'sk-it\'s-$secret'`,
+		}},
+		{"powershell", []string{
+			"Remove-Item Env:ANTHROPIC_MODEL -ErrorAction SilentlyContinue",
+			`$Env:ANTHROPIC_AUTH_TOKEN = 'sk-it''s-$secret'`,
+		}},
+		{"dotenv", []string{
+			`ANTHROPIC_AUTH_TOKEN="sk-it's-\$secret"`,
+			"ANTHROPIC_BASE_URL='https://api.moonshot.cn/anthropic'",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeEnv(&buf, tt.format, pairs, unset); err != nil {
+				t.Fatalf("writeEnv() error = %v", err)
+			}
+			for _, line := range tt.want {
+				if !strings.Contains(buf.String(), line+"\n") {
+					t.Errorf("output missing %q\ngot:\n%s", line, buf.String())
+				}
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := writeEnv(&buf, "json", pairs, unset); err != nil {
+			t.Fatalf("writeEnv() error = %v", err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("output is not valid JSON: %v", err)
+		}
+		if got["ANTHROPIC_AUTH_TOKEN"] != `sk-it's-$secret` {
+			t.Errorf("ANTHROPIC_AUTH_TOKEN = %v", got["ANTHROPIC_AUTH_TOKEN"])
+		}
+		if v, exists := got["ANTHROPIC_MODEL"]; !exists || v != nil {
+			t.Errorf("ANTHROPIC_MODEL = %v, want null", v)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := writeEnv(&bytes.Buffer{}, "csh", pairs, unset); err == nil {
+			t.Error("writeEnv() should reject unknown formats")
+		}
+	})
+}
+
+func TestWriteEnvBashRoundTrip(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+
+	value := "a 'quoted' \"value\" with $HOME and \\ backslash"
+	var buf bytes.Buffer
+	if err := writeEnv(&buf, "bash", []provider.EnvPair{{Key: "CCC_TEST_VALUE", Value: value}}, nil); err != nil {
+		t.Fatalf("writeEnv() error = %v", err)
+	}
+
+	out, err := exec.Command(bash, "-c", buf.String()+`printf %s "$CCC_TEST_VALUE"`).Output()
+	if err != nil {
+		t.Fatalf("bash error = %v", err)
+	}
+	if string(out) != value {
+		t.Errorf("round trip = %q, want %q", out, value)
+	}
+}
+
+func TestRunEnvErrors(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {},
+			"auto": {"failover": []interface{}{"kimi"}},
+		},
+		Aliases: map[string]string{"a": "auto"},
+	}
+	if err := runEnv(cfg, &EnvCommand{Provider: "kmi"}); err == nil || !strings.Contains(err.Error(), "provider 'kmi' not found") {
+		t.Errorf("runEnv(kmi) error = %v, want not found", err)
+	}
+	// An alias resolves to its provider, here a failover group
+	if err := runEnv(cfg, &EnvCommand{Provider: "a"}); err == nil || !strings.Contains(err.Error(), "provider 'auto' is a failover group") {
+		t.Errorf("runEnv(a) error = %v, want the failover group error for auto", err)
+	}
+}
@@ -2,14 +2,18 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
 	"syscall"
 
 	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/gateway"
 	"github.com/guyskk/ccc/internal/provider"
+	"github.com/guyskk/ccc/internal/suggest"
 )
 
 // executeProcess replaces the current process with the specified command.
@@ -18,33 +22,109 @@ func executeProcess(path string, args []string, env []string) error {
 	return syscall.Exec(path, args, env)
 }
 
+// runProcess runs the specified command as a child process and waits for it.
+// It is used instead of executeProcess when ccc must stay alive next to the
+// child, e.g. to serve the failover gateway. Interrupts are left to the child
+// (it shares the terminal); termination signals are forwarded to it.
+// The child's exit status is returned as *ExitError.
+func runProcess(path string, args []string, env []string) error {
+	child := exec.Command(path)
+	child.Args = args
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigs)
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", path, err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig != os.Interrupt {
+					child.Process.Signal(sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := child.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			if code < 0 {
+				code = 1 // terminated by a signal
+			}
+			return &ExitError{Code: code}
+		}
+		return err
+	}
+	return nil
+}
+
 // determineProvider determines which provider to use based on the command and config.
-func determineProvider(cmd *Command, cfg *config.Config) string {
+// A provider may be named by one of its aliases or by a unique prefix of a
+// name or alias; other unknown names fall back to the current provider with
+// a suggestion, or are an error in strict mode (see isStrict). The current
+// provider is current_provider if it still exists, else the default provider
+// (see provider.GetCurrentProvider).
+func determineProvider(cmd *Command, cfg *config.Config) (string, error) {
 	if cmd.Provider != "" {
-		// User specified a provider, check if it's valid
-		if _, exists := cfg.Providers[cmd.Provider]; exists {
-			return cmd.Provider
+		// User specified a provider or an alias, check if it's valid
+		name := config.ResolveAlias(cfg, cmd.Provider)
+		if _, exists := cfg.Providers[name]; exists {
+			return name, nil
 		}
-		// Not a valid provider, try using current provider
-		if cfg.CurrentProvider != "" {
-			fmt.Printf("Unknown provider: %s\n", cmd.Provider)
-			fmt.Printf("Using current provider: %s\n", cfg.CurrentProvider)
-			return cfg.CurrentProvider
+
+		names := provider.ListProviders(cfg)
+		if match := uniquePrefix(cfg, cmd.Provider, append(names, config.AllAliases(cfg)...)); match != "" {
+			fmt.Fprintf(os.Stderr, "Using provider: %s\n", match)
+			return match, nil
 		}
-		return ""
-	}
 
-	// No provider specified, use current or first available
-	if cfg.CurrentProvider != "" {
-		return cfg.CurrentProvider
+		unknown := fmt.Sprintf("unknown provider '%s'", cmd.Provider)
+		if match := suggest.Closest(cmd.Provider, names); match != "" {
+			unknown += fmt.Sprintf(" (did you mean '%s'?)", match)
+		}
+		current := provider.GetCurrentProvider(cfg)
+		if isStrict(cfg) || current == "" {
+			return "", errors.New(unknown)
+		}
+		// Not a valid provider, try using current provider
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", unknown)
+		fmt.Fprintf(os.Stderr, "Using current provider: %s\n", current)
+		return current, nil
 	}
 
-	// Use the first available provider
-	for name := range cfg.Providers {
-		return name
-	}
+	// No provider specified, use current, default or first declared
+	return provider.GetCurrentProvider(cfg), nil
+}
 
-	return ""
+// uniquePrefix returns the provider of the names (provider names or
+// aliases) starting with prefix, ignoring case, or "" if they do not all
+// refer to the same provider.
+func uniquePrefix(cfg *config.Config, prefix string, names []string) string {
+	match := ""
+	for _, name := range names {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+			resolved := config.ResolveAlias(cfg, name)
+			if match != "" && match != resolved {
+				return ""
+			}
+			match = resolved
+		}
+	}
+	return match
 }
 
 // runClaude executes the claude command for the given provider.
@@ -54,16 +134,41 @@ func determineProvider(cmd *Command, cfg *config.Config) string {
 // than settings.json env).
 func runClaude(cfg *config.Config, cmd *Command) error {
 	// Determine which provider to use
-	providerName := determineProvider(cmd, cfg)
+	providerName, err := determineProvider(cmd, cfg)
+	if err != nil {
+		return err
+	}
 	if providerName == "" {
 		return fmt.Errorf("no providers configured")
 	}
 
-	// Switch provider and clean up supervisor hooks
-	result, err := provider.SwitchWithHook(cfg, providerName)
+	// Switch provider and clean up supervisor hooks. An isolated session
+	// leaves settings.json and ccc.json untouched, so concurrent sessions
+	// with different providers cannot affect each other.
+	isolated := isIsolated(cfg)
+	var result *provider.SwitchResult
+	if isolated {
+		result, err = provider.PrepareIsolated(cfg, providerName)
+	} else {
+		result, err = provider.SwitchWithHook(cfg, providerName)
+	}
 	if err != nil {
 		return fmt.Errorf("error switching provider: %w", err)
 	}
+
+	// A failover group is served through an in-process gateway, so claude
+	// runs as a child process instead of replacing ccc.
+	var gatewayServer *gateway.Server
+	if failoverMembers(cfg, providerName) != nil {
+		server, gatewayEnv, err := startFailoverGateway(cfg, providerName)
+		if err != nil {
+			return fmt.Errorf("error starting failover gateway: %w", err)
+		}
+		defer server.Close()
+		gatewayServer = server
+		result.ProviderEnv = config.MergeEnvMaps(result.ProviderEnv, gatewayEnv)
+		result.EnvVars = provider.EnvMapToPairs(result.ProviderEnv)
+	}
 	fmt.Printf("Launching with provider: %s\n", providerName)
 
 	// Find claude executable path
@@ -96,37 +201,126 @@ func runClaude(cfg *config.Config, cmd *Command) error {
 	// than User settings (level 5, ~/.claude/settings.json). This ensures provider
 	// env overrides any conflicting keys in settings.json without modifying the file.
 	// See docs/discuss-20260609-env-override.md for the empirical proof.
-	if result.ProviderEnv != nil && len(result.ProviderEnv) > 0 {
-		settingsJSON, err := buildProviderSettingsJSON(result.ProviderEnv)
-		if err != nil {
-			return fmt.Errorf("failed to build provider settings: %w", err)
-		}
+	// In an isolated session the non-env settings travel the same way.
+	// A failover gateway session also clears apiKeyHelper this way.
+	var sessionSettings map[string]interface{}
+	if isolated {
+		sessionSettings = result.Settings
+	}
+	if gatewayServer != nil {
+		sessionSettings = gatewaySessionSettings(sessionSettings)
+	}
+	settingsJSON, err := buildSessionSettingsJSON(sessionSettings, result.ProviderEnv)
+	if err != nil {
+		return fmt.Errorf("failed to build provider settings: %w", err)
+	}
+	if settingsJSON != "" {
 		execArgs = append(execArgs, "--settings", settingsJSON)
 	}
 
-	// Build environment variables
-	// Start with current process environment
-	env := os.Environ()
+	env := buildProcessEnv(os.Environ(), result.EnvVars)
 
-	// Remove existing environment variables to ensure provider config takes precedence
-	prefixes := []string{"CLAUDE_", "ANTHROPIC_"}
-	env = filterEnvVars(env, func(key string) bool {
-		for _, prefix := range prefixes {
-			if strings.HasPrefix(key, prefix) {
-				return false
-			}
+	if gatewayServer != nil {
+		return runProcess(claudePath, execArgs, env)
+	}
+
+	// Execute the process (replaces current process, does not return on success)
+	return executeProcess(claudePath, execArgs, env)
+}
+
+// isIsolated reports whether claude should be launched as an isolated session,
+// enabled by "isolated": true in ccc.json or CCC_ISOLATED=1.
+func isIsolated(cfg *config.Config) bool {
+	if v := os.Getenv("CCC_ISOLATED"); v != "" {
+		return v == "1" || strings.EqualFold(v, "true")
+	}
+	return cfg.Isolated
+}
+
+// isStrict reports whether unknown provider names are an error instead of
+// falling back to the current provider, enabled by "strict_provider": true
+// in ccc.json or CCC_STRICT=1.
+func isStrict(cfg *config.Config) bool {
+	if v := os.Getenv("CCC_STRICT"); v != "" {
+		return v == "1" || strings.EqualFold(v, "true")
+	}
+	return cfg.StrictProvider
+}
+
+// strippedEnvPrefixes lists the prefixes of inherited environment variables
+// that are removed before launching, so provider config takes precedence.
+var strippedEnvPrefixes = []string{"CLAUDE_", "ANTHROPIC_"}
+
+// isStrippedEnvKey reports whether an inherited environment variable is removed at launch.
+func isStrippedEnvKey(key string) bool {
+	for _, prefix := range strippedEnvPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
 		}
-		return true
+	}
+	return false
+}
+
+// buildProcessEnv builds the environment for a launched process: the inherited
+// environment without ANTHROPIC_*/CLAUDE_* variables and without variables the
+// provider sets, followed by the provider env pairs.
+func buildProcessEnv(inherited []string, pairs []provider.EnvPair) []string {
+	defined := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		defined[pair.Key] = true
+	}
+
+	env := filterEnvVars(inherited, func(key string) bool {
+		return !isStrippedEnvKey(key) && !defined[key]
 	})
+	return append(env, provider.EnvPairsToStrings(pairs)...)
+}
 
-	// Add merged provider env variables
-	if result.EnvVars != nil {
-		envPairs := provider.EnvPairsToStrings(result.EnvVars)
-		env = append(env, envPairs...)
+// runExec runs an arbitrary command with the provider's merged env.
+// Unlike runClaude it never touches settings.json or current_provider.
+func runExec(cfg *config.Config, opts *ExecCommand) error {
+	if len(opts.Command) == 0 {
+		return fmt.Errorf("usage: ccc exec [provider] -- <command> [args...]")
 	}
 
-	// Execute the process (replaces current process, does not return on success)
-	return executeProcess(claudePath, execArgs, env)
+	providerName := opts.Provider
+	if providerName == "" {
+		providerName = provider.GetCurrentProvider(cfg)
+		if providerName == "" {
+			return fmt.Errorf("no providers configured")
+		}
+	}
+	providerName, err := provider.Resolve(cfg, providerName)
+	if err != nil {
+		return err
+	}
+
+	envMap, err := provider.ProviderEnv(cfg, providerName)
+	if err != nil {
+		return err
+	}
+
+	var gatewayServer *gateway.Server
+	if failoverMembers(cfg, providerName) != nil {
+		server, gatewayEnv, err := startFailoverGateway(cfg, providerName)
+		if err != nil {
+			return fmt.Errorf("error starting failover gateway: %w", err)
+		}
+		defer server.Close()
+		gatewayServer = server
+		envMap = config.MergeEnvMaps(envMap, gatewayEnv)
+	}
+
+	path, err := exec.LookPath(opts.Command[0])
+	if err != nil {
+		return fmt.Errorf("command not found: %s", opts.Command[0])
+	}
+	env := buildProcessEnv(os.Environ(), provider.EnvMapToPairs(envMap))
+
+	if gatewayServer != nil {
+		return runProcess(path, opts.Command, env)
+	}
+	return executeProcess(path, opts.Command, env)
 }
 
 // filterEnvVars filters environment variables based on a predicate function
@@ -153,14 +347,31 @@ func filterEnvVars(env []string, shouldKeep func(string) bool) []string {
 // Environment variable references like ${VAR} are expanded before serialization.
 // Returns empty string if the resulting env map is empty.
 func buildProviderSettingsJSON(providerEnv map[string]interface{}) (string, error) {
+	return buildSessionSettingsJSON(nil, providerEnv)
+}
+
+// buildSessionSettingsJSON is like buildProviderSettingsJSON but also carries
+// non-env settings, as used by isolated sessions where settings.json is not
+// written. An "env" key in settings is ignored in favor of providerEnv.
+// Returns empty string if there is nothing to pass.
+func buildSessionSettingsJSON(settings, providerEnv map[string]interface{}) (string, error) {
+	sessionSettings := make(map[string]interface{}, len(settings)+1)
+	for k, v := range settings {
+		if k != "env" {
+			sessionSettings[k] = v
+		}
+	}
 	if len(providerEnv) == 0 {
-		return "", nil
+		if len(sessionSettings) == 0 {
+			return "", nil
+		}
+		return marshalSessionSettings(sessionSettings)
 	}
 
 	// Start with provider env, expanding ${VAR} references
 	settingsEnv := make(map[string]interface{}, len(providerEnv))
 	for k, v := range providerEnv {
-		settingsEnv[k] = os.ExpandEnv(fmt.Sprintf("%v", v))
+		settingsEnv[k] = provider.ExpandValue(v)
 	}
 
 	// Load settings.json to detect potential conflict keys.
@@ -179,13 +390,12 @@ func buildProviderSettingsJSON(providerEnv map[string]interface{}) (string, erro
 		}
 	}
 
-	if len(settingsEnv) == 0 {
-		return "", nil
-	}
+	sessionSettings["env"] = settingsEnv
+	return marshalSessionSettings(sessionSettings)
+}
 
-	settings := map[string]interface{}{
-		"env": settingsEnv,
-	}
+// marshalSessionSettings serializes settings for the --settings parameter.
+func marshalSessionSettings(settings map[string]interface{}) (string, error) {
 	data, err := json.Marshal(settings)
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal provider settings: %w", err)
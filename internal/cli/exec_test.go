@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/provider"
 )
 
 // writeSettingsJSON writes a settings.json into the test config dir.
@@ -229,3 +231,217 @@ func TestBuildProviderSettingsJSON_ProviderOverridesNotNulled(t *testing.T) {
 		t.Errorf("ANTHROPIC_BASE_URL = %v, want https://new.example.com", envMap["ANTHROPIC_BASE_URL"])
 	}
 }
+
+func TestBuildSessionSettingsJSON(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	settings := map[string]interface{}{
+		"alwaysThinkingEnabled": true,
+		"env":                   map[string]interface{}{"IGNORED": "1"},
+	}
+	providerEnv := map[string]interface{}{
+		"ANTHROPIC_BASE_URL": "https://api.example.com",
+	}
+
+	result, err := buildSessionSettingsJSON(settings, providerEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("result is not valid JSON: %v", err)
+	}
+	if parsed["alwaysThinkingEnabled"] != true {
+		t.Errorf("alwaysThinkingEnabled = %v, want true", parsed["alwaysThinkingEnabled"])
+	}
+	env := parsed["env"].(map[string]interface{})
+	if _, ok := env["IGNORED"]; ok {
+		t.Error("env from settings should be replaced by provider env")
+	}
+	if env["ANTHROPIC_BASE_URL"] != "https://api.example.com" {
+		t.Errorf("ANTHROPIC_BASE_URL = %v", env["ANTHROPIC_BASE_URL"])
+	}
+
+	t.Run("settings without env", func(t *testing.T) {
+		result, err := buildSessionSettingsJSON(map[string]interface{}{"model": "opus"}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != `{"model":"opus"}` {
+			t.Errorf("result = %s", result)
+		}
+	})
+
+	t.Run("nothing to pass", func(t *testing.T) {
+		result, err := buildSessionSettingsJSON(nil, nil)
+		if err != nil || result != "" {
+			t.Errorf("result = %q, err = %v, want empty", result, err)
+		}
+	})
+}
+
+func TestIsIsolated(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		isolated bool
+		want     bool
+	}{
+		{"default", "", false, false},
+		{"config", "", true, true},
+		{"env enables", "1", false, true},
+		{"env true", "true", false, true},
+		{"env disables config", "0", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CCC_ISOLATED", tt.env)
+			if got := isIsolated(&config.Config{Isolated: tt.isolated}); got != tt.want {
+				t.Errorf("isIsolated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExecArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantProvider string
+		wantCommand  []string
+	}{
+		{"provider and separator", []string{"kimi", "--", "python", "x.py"}, "kimi", []string{"python", "x.py"}},
+		{"provider without separator", []string{"kimi", "env"}, "kimi", []string{"env"}},
+		{"current provider", []string{"--", "env"}, "", []string{"env"}},
+		{"command flags are preserved", []string{"glm", "--", "curl", "-s", "--", "x"}, "glm", []string{"curl", "-s", "--", "x"}},
+		{"no command", []string{"kimi"}, "kimi", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Parse(append([]string{"exec"}, tt.args...))
+			if !cmd.Exec {
+				t.Fatal("Exec = false, want true")
+			}
+			if cmd.ExecOpts.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, want %q", cmd.ExecOpts.Provider, tt.wantProvider)
+			}
+			if strings.Join(cmd.ExecOpts.Command, " ") != strings.Join(tt.wantCommand, " ") {
+				t.Errorf("Command = %v, want %v", cmd.ExecOpts.Command, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestBuildProcessEnv(t *testing.T) {
+	inherited := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/user",
+		"ANTHROPIC_MODEL=stale-model",
+		"CLAUDE_CODE_USE_BEDROCK=1",
+		"API_TIMEOUT=1000",
+	}
+	pairs := []provider.EnvPair{
+		{Key: "ANTHROPIC_BASE_URL", Value: "https://api.moonshot.cn/anthropic"},
+		{Key: "API_TIMEOUT", Value: "30000"},
+	}
+
+	env := buildProcessEnv(inherited, pairs)
+
+	counts := make(map[string]int)
+	values := make(map[string]string)
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		counts[key]++
+		values[key] = value
+	}
+
+	if values["PATH"] != "/usr/bin" || values["HOME"] != "/home/user" {
+		t.Errorf("unrelated variables should be inherited, got %v", env)
+	}
+	if _, exists := values["ANTHROPIC_MODEL"]; exists {
+		t.Error("inherited ANTHROPIC_* variables should be removed")
+	}
+	if _, exists := values["CLAUDE_CODE_USE_BEDROCK"]; exists {
+		t.Error("inherited CLAUDE_* variables should be removed")
+	}
+	if counts["API_TIMEOUT"] != 1 || values["API_TIMEOUT"] != "30000" {
+		t.Errorf("API_TIMEOUT should be set once to the provider value, got %d x %q", counts["API_TIMEOUT"], values["API_TIMEOUT"])
+	}
+	if values["ANTHROPIC_BASE_URL"] != "https://api.moonshot.cn/anthropic" {
+		t.Errorf("ANTHROPIC_BASE_URL = %q", values["ANTHROPIC_BASE_URL"])
+	}
+}
+
+func TestRunExecErrors(t *testing.T) {
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://api.moonshot.cn/anthropic"}},
+		},
+		Aliases: map[string]string{"k": "kimi"},
+	}
+
+	tests := []struct {
+		name    string
+		opts    *ExecCommand
+		wantErr string
+	}{
+		{"no command", &ExecCommand{Provider: "kimi"}, "usage: ccc exec"},
+		{"unknown provider", &ExecCommand{Provider: "kmi", Command: []string{"env"}}, "provider 'kmi' not found"},
+		{"alias", &ExecCommand{Provider: "k", Command: []string{"ccc-no-such-command"}}, "command not found"},
+		{"unknown command", &ExecCommand{Provider: "kimi", Command: []string{"ccc-no-such-command"}}, "command not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runExec(cfg, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runExec() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunExecFailoverReplacesAPIKey(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	cfg := &config.Config{
+		Settings: map[string]interface{}{
+			"env":          map[string]interface{}{"ANTHROPIC_API_KEY": "sk-real"},
+			"apiKeyHelper": "echo sk-helper",
+		},
+		Providers: map[string]map[string]interface{}{
+			"kimi": {"env": map[string]interface{}{
+				"ANTHROPIC_BASE_URL":   "https://api.moonshot.cn/anthropic",
+				"ANTHROPIC_AUTH_TOKEN": "sk-kimi",
+			}},
+			"auto": {"failover": []interface{}{"kimi"}},
+		},
+	}
+
+	out := filepath.Join(t.TempDir(), "env")
+	script := `printf '%s|%s|%s' "$ANTHROPIC_BASE_URL" "$ANTHROPIC_AUTH_TOKEN" "$ANTHROPIC_API_KEY" > "$1"`
+	if err := runExec(cfg, &ExecCommand{Provider: "auto", Command: []string{"sh", "-c", script, "sh", out}}); err != nil {
+		t.Fatalf("runExec() error = %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read env: %v", err)
+	}
+	parts := strings.Split(string(data), "|")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "http://127.0.0.1:") {
+		t.Fatalf("env = %q, want the gateway URL", data)
+	}
+	if parts[1] == "" || parts[1] == "sk-kimi" {
+		t.Errorf("ANTHROPIC_AUTH_TOKEN = %q, want the gateway token", parts[1])
+	}
+	if parts[2] != "" {
+		t.Errorf("ANTHROPIC_API_KEY = %q, want it blanked for the gateway", parts[2])
+	}
+
+	settings := gatewaySessionSettings(cfg.Settings)
+	if settings["apiKeyHelper"] != "" || cfg.Settings["apiKeyHelper"] != "echo sk-helper" {
+		t.Errorf("gatewaySessionSettings() apiKeyHelper = %v, want cleared in a copy", settings["apiKeyHelper"])
+	}
+}
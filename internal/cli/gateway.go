@@ -0,0 +1,189 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/gateway"
+	"github.com/guyskk/ccc/internal/provider"
+)
+
+// defaultGatewayListen is the address used by `ccc gateway` when --listen is not given.
+const defaultGatewayListen = "127.0.0.1:8787"
+
+// failoverMembers returns the members of the failover group with the given
+// name or alias, or nil if the provider is not a failover group. Members
+// may be aliases too, buildUpstreams resolves them.
+func failoverMembers(cfg *config.Config, providerName string) []string {
+	resolved, err := config.ResolveProvider(cfg, config.ResolveAlias(cfg, providerName))
+	if err != nil {
+		return nil
+	}
+	return config.GetFailover(resolved)
+}
+
+// buildUpstreams converts provider names into gateway upstreams.
+// A single failover group name is expanded to its members.
+func buildUpstreams(cfg *config.Config, names []string) ([]gateway.Upstream, error) {
+	if len(names) == 1 {
+		if members := failoverMembers(cfg, names[0]); members != nil {
+			names = members
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no providers specified for gateway")
+	}
+
+	upstreams := make([]gateway.Upstream, 0, len(names))
+	for _, name := range names {
+		name, err := provider.Resolve(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		if failoverMembers(cfg, name) != nil {
+			return nil, fmt.Errorf("provider '%s' is a failover group and cannot be nested", name)
+		}
+		// Members are reached like 'ccc validate' reaches them
+		endpoint, envMap, err := providerEndpoint(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		envString := func(key string) string {
+			if v, ok := envMap[key]; ok {
+				return provider.ExpandValue(v)
+			}
+			return ""
+		}
+		upstream := gateway.Upstream{
+			Name:           name,
+			BaseURL:        endpoint.BaseURL,
+			AuthToken:      endpoint.AuthToken,
+			APIKey:         endpoint.APIKey,
+			AuthStyle:      endpoint.AuthStyle,
+			Headers:        endpoint.Headers,
+			Model:          envString("ANTHROPIC_MODEL"),
+			SmallFastModel: envString("ANTHROPIC_SMALL_FAST_MODEL"),
+		}
+		if endpoint.Network != nil {
+			// No timeout, responses may be long SSE streams
+			if upstream.Client, err = endpoint.NewHTTPClient(0); err != nil {
+				return nil, fmt.Errorf("provider '%s': %w", name, err)
+			}
+		}
+		upstreams = append(upstreams, upstream)
+	}
+	return upstreams, nil
+}
+
+// startFailoverGateway starts an in-process gateway for a failover group on a
+// random local port. It returns the env that points Claude Code at the gateway.
+func startFailoverGateway(cfg *config.Config, providerName string) (*gateway.Server, map[string]interface{}, error) {
+	upstreams, err := buildUpstreams(cfg, []string{providerName})
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := gateway.GenerateToken()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gw := gateway.New(upstreams, token)
+	// Claude Code owns the terminal, so failover diagnostics go to a log file.
+	if logger := openGatewayLog(); logger != nil {
+		gw.Logf = logger.Printf
+	}
+	server, err := gw.Start("127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The gateway token replaces the real credentials, so that an
+	// ANTHROPIC_API_KEY from the base settings is not sent to the gateway
+	env := map[string]interface{}{
+		"ANTHROPIC_BASE_URL":   server.URL,
+		"ANTHROPIC_AUTH_TOKEN": token,
+		"ANTHROPIC_API_KEY":    "",
+	}
+	if upstreams[0].Model != "" {
+		env["ANTHROPIC_MODEL"] = upstreams[0].Model
+	}
+	if upstreams[0].SmallFastModel != "" {
+		env["ANTHROPIC_SMALL_FAST_MODEL"] = upstreams[0].SmallFastModel
+	}
+	return server, env, nil
+}
+
+// gatewaySessionSettings returns a copy of settings for a session served
+// through a failover gateway, with apiKeyHelper cleared: Claude Code would
+// otherwise authenticate to the gateway with the helper's real key.
+func gatewaySessionSettings(settings map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(settings)+1)
+	for k, v := range settings {
+		result[k] = v
+	}
+	result["apiKeyHelper"] = ""
+	return result
+}
+
+// openGatewayLog opens the gateway log file under ~/.claude/ccc/.
+// Returns nil if the file cannot be opened.
+func openGatewayLog() *log.Logger {
+	logDir := filepath.Join(config.GetDir(), "ccc")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(logDir, "gateway.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil
+	}
+	return log.New(f, "", log.LstdFlags)
+}
+
+// runGateway executes the gateway command, serving in the foreground until interrupted.
+func runGateway(cfg *config.Config, opts *GatewayCommand) error {
+	upstreams, err := buildUpstreams(cfg, opts.Providers)
+	if err != nil {
+		return err
+	}
+
+	token := opts.Token
+	if token == "" {
+		token, err = gateway.GenerateToken()
+		if err != nil {
+			return err
+		}
+	}
+
+	gw := gateway.New(upstreams, token)
+	gw.Logf = log.New(os.Stderr, "", log.LstdFlags).Printf
+
+	listen := opts.Listen
+	if listen == "" {
+		listen = defaultGatewayListen
+	}
+	server, err := gw.Start(listen)
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+
+	fmt.Printf("Gateway listening on %s\n", server.URL)
+	fmt.Println("Providers (in failover order):")
+	for _, upstream := range upstreams {
+		fmt.Printf("  %s  %s\n", upstream.Name, upstream.BaseURL)
+	}
+	fmt.Println("\nPoint clients at the gateway with:")
+	fmt.Printf("  export ANTHROPIC_BASE_URL=%s\n", server.URL)
+	fmt.Printf("  export ANTHROPIC_AUTH_TOKEN=%s\n", token)
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	<-sigs
+	fmt.Println("\nGateway stopped")
+	return nil
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func TestParseGatewayArgs(t *testing.T) {
+	cmd := Parse([]string{"gateway", "--listen", "127.0.0.1:9000", "kimi", "glm"})
+	if !cmd.Gateway {
+		t.Fatal("Gateway = false, want true")
+	}
+	if cmd.GatewayOpts.Listen != "127.0.0.1:9000" {
+		t.Errorf("Listen = %q, want 127.0.0.1:9000", cmd.GatewayOpts.Listen)
+	}
+	if strings.Join(cmd.GatewayOpts.Providers, ",") != "kimi,glm" {
+		t.Errorf("Providers = %v, want [kimi glm]", cmd.GatewayOpts.Providers)
+	}
+}
+
+func TestBuildUpstreams(t *testing.T) {
+	t.Setenv("CCC_TEST_GLM_TOKEN", "sk-glm-from-env")
+
+	cfg := &config.Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi": {
+				"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL":         "https://api.moonshot.cn/anthropic",
+					"ANTHROPIC_AUTH_TOKEN":       "sk-kimi",
+					"ANTHROPIC_MODEL":            "kimi-k2-thinking",
+					"ANTHROPIC_SMALL_FAST_MODEL": "kimi-k2-0905-preview",
+				},
+			},
+			"glm": {
+				"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL":   "https://open.bigmodel.cn/api/anthropic",
+					"ANTHROPIC_AUTH_TOKEN": "${CCC_TEST_GLM_TOKEN}",
+				},
+				"proxy": "http://proxy.corp:3128",
+			},
+			"minimax": {
+				"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL": "https://api.minimaxi.com/anthropic",
+					"ANTHROPIC_API_KEY":  "sk-minimax",
+				},
+				"headers": map[string]interface{}{"X-Region": "cn"},
+			},
+			"auto":    {"failover": []interface{}{"kimi", "glm"}},
+			"nested":  {"failover": []interface{}{"auto"}},
+			"byalias": {"failover": []interface{}{"k", "glm"}, "aliases": []interface{}{"ba"}},
+			"nourl":   {"env": map[string]interface{}{"ANTHROPIC_AUTH_TOKEN": "x"}},
+			"badca": {
+				"env":     map[string]interface{}{"ANTHROPIC_BASE_URL": "https://llm.corp.example"},
+				"ca_file": "/nonexistent/ca.pem",
+			},
+		},
+		Aliases: map[string]string{"k": "kimi", "a": "auto"},
+	}
+
+	t.Run("failover group expands to members", func(t *testing.T) {
+		upstreams, err := buildUpstreams(cfg, []string{"auto"})
+		if err != nil {
+			t.Fatalf("buildUpstreams() error = %v", err)
+		}
+		if len(upstreams) != 2 || upstreams[0].Name != "kimi" || upstreams[1].Name != "glm" {
+			t.Fatalf("upstreams = %+v, want kimi then glm", upstreams)
+		}
+		if upstreams[0].SmallFastModel != "kimi-k2-0905-preview" {
+			t.Errorf("SmallFastModel = %q", upstreams[0].SmallFastModel)
+		}
+		if upstreams[1].AuthToken != "sk-glm-from-env" {
+			t.Errorf("AuthToken = %q, want expanded env reference", upstreams[1].AuthToken)
+		}
+		if upstreams[0].Client != nil {
+			t.Error("kimi has no network settings and should use the gateway's client")
+		}
+	})
+
+	t.Run("member proxy", func(t *testing.T) {
+		upstreams, err := buildUpstreams(cfg, []string{"auto"})
+		if err != nil {
+			t.Fatalf("buildUpstreams() error = %v", err)
+		}
+		transport, ok := upstreams[1].Client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("glm client = %+v, want its own transport", upstreams[1].Client)
+		}
+		req, _ := http.NewRequest("POST", upstreams[1].BaseURL, nil)
+		if proxyURL, err := transport.Proxy(req); err != nil || proxyURL.String() != "http://proxy.corp:3128" {
+			t.Errorf("glm proxy = %v, %v, want http://proxy.corp:3128", proxyURL, err)
+		}
+	})
+
+	t.Run("API key member with headers", func(t *testing.T) {
+		upstreams, err := buildUpstreams(cfg, []string{"kimi", "minimax"})
+		if err != nil {
+			t.Fatalf("buildUpstreams() error = %v", err)
+		}
+		if upstreams[1].APIKey != "sk-minimax" || upstreams[1].AuthToken != "" || upstreams[1].Headers["X-Region"] != "cn" {
+			t.Errorf("upstream = %+v, want the API key only and its headers", upstreams[1])
+		}
+	})
+
+	t.Run("apiKeyHelper member", func(t *testing.T) {
+		original := config.RunAPIKeyHelper
+		defer func() { config.RunAPIKeyHelper = original }()
+		config.RunAPIKeyHelper = func(command string) (string, error) { return "sk-from-helper", nil }
+
+		helperCfg := &config.Config{
+			Settings: map[string]interface{}{"apiKeyHelper": "print-key"},
+			Providers: map[string]map[string]interface{}{
+				"corp": {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://llm.corp.example"}},
+			},
+		}
+		upstreams, err := buildUpstreams(helperCfg, []string{"corp"})
+		if err != nil {
+			t.Fatalf("buildUpstreams() error = %v", err)
+		}
+		if upstreams[0].AuthToken != "sk-from-helper" {
+			t.Errorf("AuthToken = %q, want the apiKeyHelper output", upstreams[0].AuthToken)
+		}
+	})
+
+	t.Run("explicit provider list", func(t *testing.T) {
+		upstreams, err := buildUpstreams(cfg, []string{"glm", "kimi"})
+		if err != nil {
+			t.Fatalf("buildUpstreams() error = %v", err)
+		}
+		if upstreams[0].Name != "glm" {
+			t.Errorf("first upstream = %q, want glm", upstreams[0].Name)
+		}
+	})
+
+	t.Run("aliases", func(t *testing.T) {
+		for _, names := range [][]string{{"k", "glm"}, {"a"}, {"byalias"}, {"ba"}} {
+			upstreams, err := buildUpstreams(cfg, names)
+			if err != nil {
+				t.Fatalf("buildUpstreams(%v) error = %v", names, err)
+			}
+			if len(upstreams) != 2 || upstreams[0].Name != "kimi" || upstreams[1].Name != "glm" {
+				t.Errorf("buildUpstreams(%v) = %+v, want kimi then glm", names, upstreams)
+			}
+		}
+		if err := config.CheckProvider(cfg, "byalias"); err != nil {
+			t.Errorf("CheckProvider(byalias) error = %v, alias members are allowed", err)
+		}
+	})
+
+	errorTests := []struct {
+		name    string
+		names   []string
+		wantErr string
+	}{
+		{"no providers", nil, "no providers specified"},
+		{"nested group", []string{"nested"}, "cannot be nested"},
+		{"unknown provider", []string{"kimi", "missing"}, "not found"},
+		{"missing base url", []string{"nourl"}, "no ANTHROPIC_BASE_URL"},
+		{"unreadable ca_file", []string{"badca"}, "ca_file"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := buildUpstreams(cfg, tt.names)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("buildUpstreams() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
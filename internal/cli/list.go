@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/prettyjson"
+	"github.com/guyskk/ccc/internal/provider"
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+// validationStatus is the recorded outcome of the last 'ccc validate' of a provider.
+type validationStatus struct {
+	Status    string    `json:"status"` // valid, invalid or warning, see validate.ValidationResult.Status
+	CheckedAt time.Time `json:"checked_at"`
+	Message   string    `json:"message,omitempty"` // Why the provider is invalid or has a warning
+}
+
+// getValidationCachePath returns the path of the last validation statuses.
+func getValidationCachePath() string {
+	return filepath.Join(config.GetDir(), "ccc", "cache", "validate.json")
+}
+
+// readValidationCache reads the last validation statuses, keyed by provider.
+// A missing or corrupt cache is empty.
+func readValidationCache() map[string]validationStatus {
+	cache := make(map[string]validationStatus)
+	data, err := os.ReadFile(getValidationCachePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(map[string]validationStatus)
+	}
+	return cache
+}
+
+// recordValidation records the validation results for 'ccc list'.
+func recordValidation(results []*validate.ValidationResult) {
+	cache := readValidationCache()
+	now := time.Now()
+	for _, result := range results {
+		status := validationStatus{Status: result.Status(), CheckedAt: now}
+		switch {
+		case len(result.Errors) > 0:
+			status.Message = result.Errors[0]
+		case result.APIError != nil:
+			status.Message = result.APIError.Error()
+		case result.Capabilities != nil && !result.Capabilities.Required():
+			status.Message = "deep checks failed: " + result.Capabilities.String()
+		}
+		cache[result.Provider] = status
+	}
+	writeCacheFile(getValidationCachePath(), cache)
+}
+
+// listEntry describes a provider in 'ccc list'.
+type listEntry struct {
+	Name       string            `json:"name"`
+	Aliases    []string          `json:"aliases,omitempty"`
+	BaseURL    string            `json:"base_url,omitempty"`
+	Host       string            `json:"host,omitempty"`
+	Model      string            `json:"model,omitempty"`
+	Failover   []string          `json:"failover,omitempty"`
+	Current    bool              `json:"current"`
+	Default    bool              `json:"default"`
+	Error      string            `json:"error,omitempty"`      // Configuration error
+	Validation *validationStatus `json:"validation,omitempty"` // Last 'ccc validate', nil if never validated
+}
+
+// listEntries describes the providers in declaration order, without
+// resolving secrets or touching the network.
+func listEntries(cfg *config.Config) []listEntry {
+	current := provider.GetCurrentProvider(cfg)
+	statuses := readValidationCache()
+
+	entries := make([]listEntry, 0, len(cfg.Providers))
+	for _, name := range provider.ListProviders(cfg) {
+		entry := listEntry{
+			Name:    name,
+			Aliases: config.GetAliases(cfg, name),
+			Current: name == current,
+			Default: name == cfg.DefaultProvider,
+		}
+		if status, ok := statuses[name]; ok {
+			entry.Validation = &status
+		}
+
+		resolved, err := config.ResolveProvider(cfg, name)
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			continue
+		}
+		entry.Failover = config.GetFailover(resolved)
+		if entry.Failover == nil {
+			settings := config.DeepMerge(cfg.Settings, config.ProviderSettings(resolved))
+			entry.BaseURL = os.ExpandEnv(config.GetBaseURL(settings))
+			entry.Model = os.ExpandEnv(config.GetModel(settings))
+			if u, err := url.Parse(entry.BaseURL); err == nil {
+				entry.Host = u.Host
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// runList lists the providers. With --quiet nothing is printed, and the
+// exit status is 1 if no providers are configured.
+func runList(w io.Writer, opts *ListCommand) error {
+	if opts.Err != nil {
+		return fmt.Errorf("%v\nusage: ccc list [--json] [--quiet]", opts.Err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		if opts.Quiet {
+			return &ExitError{Code: 1}
+		}
+		return err
+	}
+
+	entries := listEntries(cfg)
+	switch {
+	case opts.Quiet:
+		if len(entries) == 0 {
+			return &ExitError{Code: 1}
+		}
+		return nil
+	case opts.JSON:
+		data, err := prettyjson.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to marshal providers: %w", err)
+		}
+		fmt.Fprintf(w, "%s\n", data)
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No providers configured")
+		return nil
+	}
+	writeList(w, entries, time.Now())
+	return nil
+}
+
+// writeList prints the providers as a table, marking the current one with "*".
+func writeList(w io.Writer, entries []listEntry, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tNAME\tHOST\tMODEL\tLAST VALIDATION")
+	for _, e := range entries {
+		marker := ""
+		if e.Current {
+			marker = "*"
+		}
+		host, model := orDash(e.Host), orDash(e.Model)
+		switch {
+		case e.Error != "":
+			host, model = "(invalid config)", "-"
+		case e.Failover != nil:
+			host = "(failover)"
+			model = strings.Join(e.Failover, " -> ")
+		}
+		validation := "-"
+		if v := e.Validation; v != nil {
+			validation = fmt.Sprintf("%s, %s", v.Status, formatAge(now.Sub(v.CheckedAt)))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", marker, e.Name, host, model, validation)
+	}
+	tw.Flush()
+}
+
+// orDash returns s, or "-" if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatAge formats how long ago something happened, e.g. "3h ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// runCurrent prints the provider that 'ccc' without a provider would run:
+// current_provider, or else the default provider. It only reads the
+// configuration, so it is fast enough for shell prompts and status bars.
+// With --quiet nothing is printed, and the exit status is 1 if there is none.
+func runCurrent(w io.Writer, opts *CurrentCommand) error {
+	if opts.Err != nil {
+		return fmt.Errorf("%v\nusage: ccc current [--json] [--quiet]", opts.Err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		if opts.Quiet {
+			return &ExitError{Code: 1}
+		}
+		return err
+	}
+
+	name := provider.GetCurrentProvider(cfg)
+	switch {
+	case opts.Quiet:
+		if name == "" {
+			return &ExitError{Code: 1}
+		}
+		return nil
+	case name == "":
+		return fmt.Errorf("no providers configured")
+	case opts.JSON:
+		data, err := prettyjson.Marshal(map[string]string{"name": name})
+		if err != nil {
+			return fmt.Errorf("failed to marshal provider: %w", err)
+		}
+		fmt.Fprintf(w, "%s\n", data)
+		return nil
+	}
+	fmt.Fprintln(w, name)
+	return nil
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+func TestParseListArgs(t *testing.T) {
+	if opts := parseListArgs([]string{"--json", "-q"}); opts.Err != nil || !opts.JSON || !opts.Quiet {
+		t.Errorf("opts = %+v", opts)
+	}
+	if opts := parseListArgs([]string{"kimi"}); opts.Err == nil {
+		t.Error("extra arguments should be reported")
+	}
+	if opts := parseCurrentArgs([]string{"--quiet"}); opts.Err != nil || opts.JSON || !opts.Quiet {
+		t.Errorf("opts = %+v", opts)
+	}
+	if opts := parseCurrentArgs([]string{"--bogus"}); opts.Err == nil {
+		t.Error("unknown flags should be reported")
+	}
+
+	cmd := Parse([]string{"list", "--json"})
+	if !cmd.List || !cmd.ListOpts.JSON {
+		t.Errorf("Parse(list --json) = %+v", cmd)
+	}
+	if cmd := Parse([]string{"current"}); !cmd.Current {
+		t.Errorf("Parse(current) = %+v", cmd)
+	}
+}
+
+// exitCode returns the exit status of an error returned by a command.
+func exitCode(err error) int {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestRunList(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	var out bytes.Buffer
+	if code := exitCode(runList(&out, &ListCommand{Quiet: true})); code != 1 || out.Len() != 0 {
+		t.Errorf("list --quiet without ccc.json: exit %d, output %q", code, out.String())
+	}
+
+	writeTestConfig(t, "kimi", map[string]map[string]interface{}{
+		"kimi": {"aliases": []interface{}{"k"}, "env": map[string]interface{}{
+			"ANTHROPIC_BASE_URL":   "https://api.moonshot.cn/anthropic",
+			"ANTHROPIC_AUTH_TOKEN": "sk-secret",
+			"ANTHROPIC_MODEL":      "kimi-k2",
+		}},
+		"glm":    {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://open.bigmodel.cn/api/anthropic"}},
+		"auto":   {"failover": []interface{}{"kimi", "glm"}},
+		"broken": {"extends": "missing"},
+	})
+	recordValidation([]*validate.ValidationResult{
+		{Provider: "kimi", Valid: true, APIStatus: "ok"},
+		{Provider: "glm", Valid: false, Errors: []string{"Missing required environment variable: ANTHROPIC_AUTH_TOKEN"}},
+	})
+
+	t.Run("table", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runList(&out, &ListCommand{}); err != nil {
+			t.Fatalf("runList() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		want := [][]string{
+			{"NAME", "HOST", "MODEL", "LAST VALIDATION"},
+			{"auto", "(failover)", "kimi -> glm", "-"},
+			{"broken", "(invalid config)", "-", "-"},
+			{"glm", "open.bigmodel.cn", "-", "invalid, just now"},
+			{"kimi", "api.moonshot.cn", "kimi-k2", "valid, just now"},
+		}
+		if len(lines) != len(want) {
+			t.Fatalf("output has %d lines, want %d:\n%s", len(lines), len(want), out.String())
+		}
+		for i, fields := range want {
+			for _, field := range fields {
+				if !strings.Contains(lines[i], field) {
+					t.Errorf("line %d = %q, want %q", i, lines[i], field)
+				}
+			}
+		}
+		for i, line := range lines {
+			if current := i == 4; strings.HasPrefix(line, "*") != current {
+				t.Errorf("line %d = %q, current marker should be on kimi only", i, line)
+			}
+		}
+		if strings.Contains(out.String(), "sk-secret") {
+			t.Error("list must not print credentials")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runList(&out, &ListCommand{JSON: true}); err != nil {
+			t.Fatalf("runList() error = %v", err)
+		}
+		var entries []listEntry
+		if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+			t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+		}
+		if len(entries) != 4 {
+			t.Fatalf("got %d entries, want 4", len(entries))
+		}
+		kimi := entries[3]
+		if kimi.Name != "kimi" || !kimi.Current || kimi.Host != "api.moonshot.cn" || kimi.Model != "kimi-k2" ||
+			strings.Join(kimi.Aliases, ",") != "k" || kimi.Validation == nil || kimi.Validation.Status != "valid" {
+			t.Errorf("kimi entry = %+v", kimi)
+		}
+		if glm := entries[2]; glm.Validation == nil || !strings.Contains(glm.Validation.Message, "ANTHROPIC_AUTH_TOKEN") {
+			t.Errorf("glm entry = %+v, want the validation error", glm)
+		}
+		if broken := entries[1]; broken.Error == "" || broken.Validation != nil {
+			t.Errorf("broken entry = %+v, want a config error", broken)
+		}
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		var out bytes.Buffer
+		if code := exitCode(runList(&out, &ListCommand{Quiet: true, JSON: true})); code != 0 || out.Len() != 0 {
+			t.Errorf("list --quiet: exit %d, output %q", code, out.String())
+		}
+	})
+}
+
+func TestRunCurrent(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	tests := []struct {
+		name     string
+		current  string
+		opts     *CurrentCommand
+		want     string
+		wantCode int
+	}{
+		{"name", "glm", &CurrentCommand{}, "glm\n", 0},
+		{"json", "glm", &CurrentCommand{JSON: true}, `"name": "glm"`, 0},
+		{"quiet", "glm", &CurrentCommand{Quiet: true}, "", 0},
+		{"falls back to the first provider", "", &CurrentCommand{}, "glm\n", 0},
+		{"removed current provider", "gone", &CurrentCommand{}, "glm\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestConfig(t, tt.current, map[string]map[string]interface{}{"kimi": {}, "glm": {}})
+			var out bytes.Buffer
+			code := exitCode(runCurrent(&out, tt.opts))
+			if code != tt.wantCode || !strings.Contains(out.String(), tt.want) || (tt.want == "" && out.Len() > 0) {
+				t.Errorf("runCurrent() exit %d, output %q, want exit %d, output %q", code, out.String(), tt.wantCode, tt.want)
+			}
+		})
+	}
+
+	t.Run("no providers", func(t *testing.T) {
+		writeTestConfig(t, "", map[string]map[string]interface{}{})
+		var out bytes.Buffer
+		if code := exitCode(runCurrent(&out, &CurrentCommand{Quiet: true})); code != 1 || out.Len() != 0 {
+			t.Errorf("current --quiet: exit %d, output %q, want exit 1 and no output", code, out.String())
+		}
+		if err := runCurrent(&out, &CurrentCommand{}); err == nil {
+			t.Error("expected an error without providers")
+		}
+	})
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3*time.Hour + 20*time.Minute, "3h ago"},
+		{72 * time.Hour, "3d ago"},
+	}
+	for _, tt := range tests {
+		if got := formatAge(tt.d); got != tt.want {
+			t.Errorf("formatAge(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/picker"
+	"github.com/guyskk/ccc/internal/provider"
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+// modelsCacheTTL is how long a provider's model list is reused before it is fetched again.
+const modelsCacheTTL = 24 * time.Hour
+
+// PickFunc lets the user choose one of items.
+// This variable allows tests to override the default behavior.
+var PickFunc = picker.Pick
+
+// FetchModelsFunc fetches the model list of an endpoint.
+// This variable allows tests to override the default behavior.
+var FetchModelsFunc = validate.FetchModels
+
+// modelsCacheEntry is a cached model list of one endpoint.
+type modelsCacheEntry struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Models    []string  `json:"models"`
+}
+
+// getModelsCachePath returns the path of the model list cache.
+func getModelsCachePath() string {
+	return filepath.Join(config.GetDir(), "ccc", "cache", "models.json")
+}
+
+// modelsCacheKey identifies an endpoint in the cache. The credential is hashed,
+// so the cache never contains credentials, while different accounts on the
+// same endpoint get their own entry.
+func modelsCacheKey(baseURL, authToken string) string {
+	sum := sha256.Sum256([]byte(authToken))
+	return baseURL + "#" + hex.EncodeToString(sum[:])[:12]
+}
+
+// readModelsCache reads the cache. A missing or corrupt cache is empty.
+func readModelsCache() map[string]modelsCacheEntry {
+	cache := make(map[string]modelsCacheEntry)
+	data, err := os.ReadFile(getModelsCachePath())
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(map[string]modelsCacheEntry)
+	}
+	return cache
+}
+
+// writeModelsCache writes the cache. Failures are ignored, the cache is only an optimization.
+func writeModelsCache(cache map[string]modelsCacheEntry) {
+	writeCacheFile(getModelsCachePath(), cache)
+}
+
+// writeCacheFile writes v as JSON to a cache file, replacing it atomically.
+// Failures are ignored, caches are only an optimization.
+func writeCacheFile(path string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// providerEndpoint returns the endpoint of a provider, with its network,
+// headers and auth_style settings, and the provider's env.
+func providerEndpoint(cfg *config.Config, name string) (validate.Endpoint, map[string]interface{}, error) {
+	envMap, err := provider.ProviderEnv(cfg, name)
+	if err != nil {
+		return validate.Endpoint{}, nil, err
+	}
+	resolved, err := config.ResolveProvider(cfg, name)
+	if err != nil {
+		return validate.Endpoint{}, nil, err
+	}
+	network, err := config.GetNetwork(resolved)
+	if err != nil {
+		return validate.Endpoint{}, nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+	headers, err := config.GetHeaders(resolved)
+	if err != nil {
+		return validate.Endpoint{}, nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+	authStyle, err := config.GetAuthStyle(resolved)
+	if err != nil {
+		return validate.Endpoint{}, nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+
+	endpoint := validate.Endpoint{
+		BaseURL:   provider.ExpandValue(envMap["ANTHROPIC_BASE_URL"]),
+		AuthStyle: authStyle,
+		Headers:   headers,
+		Network:   network,
+	}
+	if envMap["ANTHROPIC_BASE_URL"] == nil || endpoint.BaseURL == "" {
+		return validate.Endpoint{}, nil, fmt.Errorf("provider '%s' has no ANTHROPIC_BASE_URL", name)
+	}
+
+	// Credentials are read from the expanded env, with the apiKeyHelper of
+	// the provider or of the base settings
+	env := make(map[string]interface{}, len(envMap))
+	for k, v := range envMap {
+		env[k] = provider.ExpandValue(v)
+	}
+	settings := map[string]interface{}{"env": env, "apiKeyHelper": config.GetAPIKeyHelper(cfg.Settings)}
+	if helper := config.GetAPIKeyHelper(resolved); helper != "" {
+		settings["apiKeyHelper"] = helper
+	}
+	if endpoint.AuthToken, endpoint.APIKey, err = config.GetCredentials(settings); err != nil {
+		return validate.Endpoint{}, nil, fmt.Errorf("provider '%s': %w", name, err)
+	}
+	return endpoint, envMap, nil
+}
+
+// listModels returns the model list of an endpoint, from the cache if it is
+// younger than modelsCacheTTL and refresh is false.
+func listModels(endpoint validate.Endpoint, refresh bool) (*modelsCacheEntry, bool, error) {
+	key := modelsCacheKey(endpoint.BaseURL, endpoint.AuthToken+endpoint.APIKey)
+	cache := readModelsCache()
+	if entry, ok := cache[key]; ok && !refresh && time.Since(entry.FetchedAt) < modelsCacheTTL {
+		return &entry, true, nil
+	}
+
+	models, err := FetchModelsFunc(endpoint)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list models of %s: %w", endpoint.BaseURL, err)
+	}
+	entry := modelsCacheEntry{FetchedAt: time.Now(), Models: models}
+	cache[key] = entry
+	writeModelsCache(cache)
+	return &entry, false, nil
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// modelKeys are the provider env keys that can be set by 'ccc models --set', in prompt order.
+var modelKeys = []string{"ANTHROPIC_MODEL", "ANTHROPIC_SMALL_FAST_MODEL"}
+
+// runModels lists the models of a provider, or lets the user pick
+// the provider's models with --set.
+func runModels(cfg *config.Config, opts *ModelsCommand) error {
+	if opts.Err != nil {
+		return fmt.Errorf("%v\nusage: ccc models [provider] [--set] [--refresh]", opts.Err)
+	}
+	providerName := opts.Provider
+	if providerName == "" {
+		providerName = provider.GetCurrentProvider(cfg)
+		if providerName == "" {
+			return fmt.Errorf("no providers configured")
+		}
+	}
+	providerName, err := provider.Resolve(cfg, providerName)
+	if err != nil {
+		return err
+	}
+	if failoverMembers(cfg, providerName) != nil {
+		return fmt.Errorf("provider '%s' is a failover group, list the models of its members instead", providerName)
+	}
+
+	endpoint, envMap, err := providerEndpoint(cfg, providerName)
+	if err != nil {
+		return err
+	}
+
+	entry, cached, err := listModels(endpoint, opts.Refresh)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]string, len(modelKeys))
+	for _, key := range modelKeys {
+		if v, ok := envMap[key]; ok {
+			current[key] = provider.ExpandValue(v)
+		}
+	}
+
+	if !opts.Set {
+		writeModels(os.Stdout, providerName, endpoint.BaseURL, entry, cached, current)
+		return nil
+	}
+	return setModels(providerName, entry.Models, current)
+}
+
+// writeModels prints the model list, marking the configured models
+// and warning about configured models that are not in the list.
+func writeModels(w io.Writer, providerName, baseURL string, entry *modelsCacheEntry, cached bool, current map[string]string) {
+	source := "fetched now"
+	if cached {
+		source = fmt.Sprintf("cached %s ago, --refresh to update", time.Since(entry.FetchedAt).Round(time.Second))
+	}
+	fmt.Fprintf(w, "Models of %s (%s, %s):\n", providerName, baseURL, source)
+	if len(entry.Models) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, model := range entry.Models {
+		line := "  " + model
+		for _, key := range modelKeys {
+			if current[key] == model {
+				line += "  (" + key + ")"
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	for _, key := range modelKeys {
+		if model := current[key]; model != "" && len(entry.Models) > 0 && !containsString(entry.Models, model) {
+			fmt.Fprintf(w, "\nWarning: %s '%s' is not in the model list\n", key, model)
+		}
+	}
+	if current["ANTHROPIC_MODEL"] == "" || !containsString(entry.Models, current["ANTHROPIC_MODEL"]) {
+		if best := validate.SelectBestModel(entry.Models); best != "" {
+			fmt.Fprintf(w, "Suggested ANTHROPIC_MODEL: %s (ccc models %s --set)\n", best, providerName)
+		}
+	}
+}
+
+// setModels lets the user pick ANTHROPIC_MODEL and ANTHROPIC_SMALL_FAST_MODEL
+// from models and writes them into the provider's env in ccc.json.
+func setModels(providerName string, models []string, current map[string]string) error {
+	if len(models) == 0 {
+		return fmt.Errorf("provider '%s' returned no models", providerName)
+	}
+
+	cfg, err := loadUserConfigForEdit(false)
+	if err != nil {
+		return err
+	}
+	if _, exists := cfg.Providers[providerName]; !exists {
+		return notInUserConfig(providerName)
+	}
+
+	// Pick first, so that the config lock is not held while waiting
+	picked := make(map[string]string, len(modelKeys))
+	for _, key := range modelKeys {
+		model := current[key]
+		if model != "" && !containsString(models, model) {
+			fmt.Fprintf(os.Stderr, "Warning: %s '%s' is not in the model list\n", key, model)
+		}
+		items := make([]picker.Item, len(models))
+		for i, m := range models {
+			items[i] = picker.Item{Label: m, Current: m == model}
+		}
+		index, err := PickFunc(fmt.Sprintf("Select %s for %s", key, providerName), items)
+		if err != nil {
+			if errors.Is(err, picker.ErrCancelled) {
+				return &ExitError{Code: 130}
+			}
+			return err
+		}
+		picked[key] = models[index]
+	}
+
+	var p map[string]interface{}
+	err = config.UpdateConfig(func(cfg *config.Config) error {
+		var exists bool
+		if p, exists = cfg.Providers[providerName]; !exists {
+			return notInUserConfig(providerName)
+		}
+		if p == nil {
+			p = map[string]interface{}{}
+			cfg.Providers[providerName] = p
+		}
+		for _, key := range modelKeys {
+			if err := config.SetPath(p, "env."+key, picked[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Set %s for provider '%s'\n", joinModelKeys(p), providerName)
+	return nil
+}
+
+// notInUserConfig reports that a provider comes from a project config.
+func notInUserConfig(providerName string) error {
+	return fmt.Errorf("provider '%s' is not defined in %s, edit its project config instead", providerName, config.GetConfigPath())
+}
+
+// joinModelKeys formats the model keys of a provider as KEY=value pairs.
+func joinModelKeys(p map[string]interface{}) string {
+	parts := make([]string, 0, len(modelKeys))
+	for _, key := range modelKeys {
+		value, _ := config.GetPath(p, "env."+key)
+		parts = append(parts, fmt.Sprintf("%s=%v", key, value))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/picker"
+	"github.com/guyskk/ccc/internal/validate"
+)
+
+// stubFetchModels replaces FetchModelsFunc and counts the calls.
+func stubFetchModels(t *testing.T, models []string) *int {
+	t.Helper()
+	original := FetchModelsFunc
+	t.Cleanup(func() { FetchModelsFunc = original })
+	calls := 0
+	FetchModelsFunc = func(endpoint validate.Endpoint) ([]string, error) {
+		calls++
+		return models, nil
+	}
+	return &calls
+}
+
+func TestParseModelsArgs(t *testing.T) {
+	opts := parseModelsArgs([]string{"kimi", "--set", "--refresh"})
+	if opts.Err != nil || opts.Provider != "kimi" || !opts.Set || !opts.Refresh {
+		t.Errorf("opts = %+v", opts)
+	}
+	if opts := parseModelsArgs([]string{"a", "b"}); opts.Err == nil {
+		t.Error("extra arguments should be reported")
+	}
+}
+
+func TestListModelsCache(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+	calls := stubFetchModels(t, []string{"m1", "m2"})
+
+	entry, cached, err := listModels(validate.Endpoint{BaseURL: "https://api.example.com", AuthToken: "sk-1"}, false)
+	if err != nil || cached || len(entry.Models) != 2 {
+		t.Fatalf("first listModels() = %+v, %v, %v", entry, cached, err)
+	}
+	if _, cached, _ := listModels(validate.Endpoint{BaseURL: "https://api.example.com", AuthToken: "sk-1"}, false); !cached || *calls != 1 {
+		t.Errorf("second call: cached = %v, calls = %d, want cache hit", cached, *calls)
+	}
+	if _, cached, _ := listModels(validate.Endpoint{BaseURL: "https://api.example.com", AuthToken: "sk-2"}, false); cached {
+		t.Error("a different token should not share the cache entry")
+	}
+	if _, cached, _ := listModels(validate.Endpoint{BaseURL: "https://api.example.com", AuthToken: "sk-1"}, true); cached {
+		t.Error("refresh should bypass the cache")
+	}
+
+	// Expired entries are fetched again
+	cache := readModelsCache()
+	key := modelsCacheKey("https://api.example.com", "sk-1")
+	cache[key] = modelsCacheEntry{FetchedAt: time.Now().Add(-modelsCacheTTL - time.Minute), Models: []string{"old"}}
+	writeModelsCache(cache)
+	entry, cached, _ = listModels(validate.Endpoint{BaseURL: "https://api.example.com", AuthToken: "sk-1"}, false)
+	if cached || entry.Models[0] != "m1" {
+		t.Errorf("expired entry: cached = %v, models = %v", cached, entry.Models)
+	}
+	if strings.Contains(key, "sk-1") {
+		t.Errorf("cache key %q contains the token", key)
+	}
+}
+
+func TestWriteModels(t *testing.T) {
+	entry := &modelsCacheEntry{FetchedAt: time.Now(), Models: []string{"claude-sonnet-4-5-20250929", "claude-haiku-4-5-20251001"}}
+
+	var buf bytes.Buffer
+	writeModels(&buf, "kimi", "https://api.example.com", entry, false, map[string]string{
+		"ANTHROPIC_MODEL":            "claude-sonnet-typo",
+		"ANTHROPIC_SMALL_FAST_MODEL": "claude-haiku-4-5-20251001",
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"claude-haiku-4-5-20251001  (ANTHROPIC_SMALL_FAST_MODEL)",
+		"Warning: ANTHROPIC_MODEL 'claude-sonnet-typo' is not in the model list",
+		"Suggested ANTHROPIC_MODEL: claude-sonnet-4-5-20250929",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "ANTHROPIC_SMALL_FAST_MODEL '") {
+		t.Errorf("listed small/fast model should not be flagged:\n%s", out)
+	}
+}
+
+func TestRunModelsSet(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+	stubFetchModels(t, []string{"m1", "m2", "m3"})
+	writeTestConfig(t, "kimi", map[string]map[string]interface{}{
+		"kimi": {"env": map[string]interface{}{
+			"ANTHROPIC_BASE_URL":   "https://api.example.com",
+			"ANTHROPIC_AUTH_TOKEN": "sk-test",
+			"ANTHROPIC_MODEL":      "m2",
+		}, "aliases": []interface{}{"k"}},
+	})
+
+	originalPick := PickFunc
+	defer func() { PickFunc = originalPick }()
+	var titles []string
+	PickFunc = func(title string, items []picker.Item) (int, error) {
+		titles = append(titles, title)
+		if len(titles) == 1 && !items[1].Current {
+			t.Errorf("configured model m2 should be preselected: %+v", items)
+		}
+		return 2, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := runModels(cfg, &ModelsCommand{Provider: "k", Set: true}); err != nil {
+		t.Fatalf("runModels() error = %v", err)
+	}
+	if len(titles) != 2 {
+		t.Errorf("picked %d times, want 2", len(titles))
+	}
+	cfg, _ = config.LoadUser()
+	env := config.GetEnv(cfg.Providers["kimi"])
+	if env["ANTHROPIC_MODEL"] != "m3" || env["ANTHROPIC_SMALL_FAST_MODEL"] != "m3" {
+		t.Errorf("env = %v", env)
+	}
+
+	PickFunc = func(title string, items []picker.Item) (int, error) { return -1, picker.ErrCancelled }
+	if err := runModels(cfg, &ModelsCommand{Set: true}); err == nil {
+		t.Error("cancelled pick should return an error")
+	}
+}
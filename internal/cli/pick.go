@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/picker"
+	"github.com/guyskk/ccc/internal/provider"
+)
+
+// providerItems returns picker items for all providers, in declaration
+// order, showing each provider's base URL and model.
+func providerItems(cfg *config.Config) []picker.Item {
+	names := provider.ListProviders(cfg)
+
+	current := provider.GetCurrentProvider(cfg)
+	items := make([]picker.Item, 0, len(names))
+	for _, name := range names {
+		items = append(items, picker.Item{
+			Label:   name,
+			Detail:  providerSummary(cfg, name),
+			Current: name == current,
+		})
+	}
+	return items
+}
+
+// providerSummary describes a provider by its base URL and model,
+// without resolving secrets or touching the network.
+func providerSummary(cfg *config.Config, name string) string {
+	resolved, err := config.ResolveProvider(cfg, name)
+	if err != nil {
+		return fmt.Sprintf("(invalid: %v)", err)
+	}
+	if members := config.GetFailover(resolved); members != nil {
+		return "failover: " + strings.Join(members, " -> ")
+	}
+
+	settings := config.DeepMerge(cfg.Settings, config.ProviderSettings(resolved))
+	parts := []string{}
+	if baseURL := config.GetBaseURL(settings); baseURL != "" {
+		parts = append(parts, baseURL)
+	}
+	if model := config.GetModel(settings); model != "" {
+		parts = append(parts, model)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// runPick lets the user pick a provider and launches claude with it.
+func runPick(cfg *config.Config, cmd *Command) error {
+	items := providerItems(cfg)
+	if len(items) == 0 {
+		return fmt.Errorf("no providers configured")
+	}
+
+	index, err := picker.Pick("Select a provider", items)
+	if err != nil {
+		if errors.Is(err, picker.ErrCancelled) {
+			return &ExitError{Code: 130}
+		}
+		return err
+	}
+
+	cmd.Provider = items[index].Label
+	return runClaude(cfg, cmd)
+}
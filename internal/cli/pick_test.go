@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func TestParsePick(t *testing.T) {
+	cmd := Parse([]string{"--pick", "--verbose"})
+	if !cmd.Pick {
+		t.Fatal("Pick = false, want true")
+	}
+	if strings.Join(cmd.ClaudeArgs, " ") != "--verbose" {
+		t.Errorf("ClaudeArgs = %v, want [--verbose]", cmd.ClaudeArgs)
+	}
+}
+
+func TestProviderItems(t *testing.T) {
+	cfg := &config.Config{
+		Settings: map[string]interface{}{
+			"env": map[string]interface{}{"ANTHROPIC_MODEL": "base-model"},
+		},
+		CurrentProvider: "kimi",
+		Providers: map[string]map[string]interface{}{
+			"kimi": {
+				"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL": "https://api.moonshot.cn/anthropic",
+					"ANTHROPIC_MODEL":    "kimi-k2-thinking",
+				},
+			},
+			"glm": {
+				"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL": "https://open.bigmodel.cn/api/anthropic",
+				},
+			},
+			"auto":   {"failover": []interface{}{"kimi", "glm"}},
+			"broken": {"extends": "missing"},
+		},
+	}
+
+	items := providerItems(cfg)
+	want := []struct {
+		label   string
+		detail  string
+		current bool
+	}{
+		{"auto", "failover: kimi -> glm", false},
+		{"broken", "(invalid: provider 'broken' extends unknown provider 'missing')", false},
+		{"glm", "https://open.bigmodel.cn/api/anthropic  base-model", false},
+		{"kimi", "https://api.moonshot.cn/anthropic  kimi-k2-thinking", true},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("got %d items, want %d", len(items), len(want))
+	}
+	for i, w := range want {
+		if items[i].Label != w.label || items[i].Detail != w.detail || items[i].Current != w.current {
+			t.Errorf("items[%d] = %+v, want %+v", i, items[i], w)
+		}
+	}
+}
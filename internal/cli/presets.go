@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/guyskk/ccc/internal/preset"
+	"github.com/guyskk/ccc/internal/prettyjson"
+)
+
+// presetsUsage describes the presets subcommands.
+const presetsUsage = `usage: ccc presets list [--json]`
+
+// runPresets executes the presets command.
+func runPresets(w io.Writer, opts *PresetsCommand) error {
+	if opts.Err != nil {
+		return fmt.Errorf("%v\n%s", opts.Err, presetsUsage)
+	}
+	if opts.Action != "" && opts.Action != "list" {
+		return fmt.Errorf("unknown presets command '%s'\n%s", opts.Action, presetsUsage)
+	}
+
+	presets, err := preset.Load()
+	if err != nil {
+		return err
+	}
+	if opts.JSON {
+		return writePresetsJSON(w, presets)
+	}
+	writePresets(w, presets)
+	return nil
+}
+
+// writePresets prints presets as a table.
+func writePresets(w io.Writer, presets []preset.Preset) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tBASE URL\tMODEL\tDESCRIPTION")
+	for _, p := range presets {
+		model := p.Model
+		if model == "" {
+			model = "-"
+		}
+		description := p.Description
+		if p.Source != preset.BuiltinSource {
+			description += " (" + p.Source + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, p.BaseURL, model, description)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nAdd one with: ccc provider add --preset <name>\n")
+	fmt.Fprintf(w, "Extra presets are read from %s/*.json\n", preset.GetDir())
+}
+
+// writePresetsJSON prints presets as a JSON array.
+func writePresetsJSON(w io.Writer, presets []preset.Preset) error {
+	type presetJSON struct {
+		Name string `json:"name"`
+		preset.Preset
+		Source string `json:"source"`
+	}
+	out := make([]presetJSON, 0, len(presets))
+	for _, p := range presets {
+		out = append(out, presetJSON{Name: p.Name, Preset: p, Source: p.Source})
+	}
+	data, err := prettyjson.Marshal(out)
+	if err != nil {
+		return fmt.Errorf("failed to marshal presets: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return nil
+}
@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/preset"
+	"github.com/guyskk/ccc/internal/prettyjson"
+	"github.com/guyskk/ccc/internal/provider"
+)
+
+// providerUsage describes the provider subcommands.
+const providerUsage = `usage: ccc provider <command> [args...]
+
+Commands:
+  add <name> --base-url <url> [--model <model>] [--small-fast-model <model>] [--token <token>] [--extends <provider>]
+  add [<name>] --preset <preset> [flags...]  Add a provider from a preset (see ccc presets list)
+  rm <name>                   Remove a provider
+  mv <old> <new>              Rename a provider, updating references and current_provider
+  cp <src> <dst>              Copy a provider
+  set <name> <path> <value>   Set a value, e.g. env.ANTHROPIC_MODEL (--json parses the value as JSON)
+  set <name> <path> --unset   Remove a value
+  get <name> [<path>]         Print a provider or one of its values`
+
+// ReadSecretFunc reads a secret such as an API token without echoing it.
+// This variable allows tests to override the default behavior.
+var ReadSecretFunc = func(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(secret)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// checkProviderName rejects names that cannot be used on the command line.
+func checkProviderName(name string) error {
+	if name == "" {
+		return fmt.Errorf("provider name must not be empty")
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("provider name '%s' must not start with '-'", name)
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("provider name '%s' must not contain whitespace", name)
+	}
+	if isReservedName(name) {
+		return fmt.Errorf("provider name '%s' is reserved for the '%s' command", name, name)
+	}
+	return nil
+}
+
+// loadUserConfigForEdit loads ~/.claude/ccc.json for modification, without
+// project config. A missing file yields an empty config when create is true.
+func loadUserConfigForEdit(create bool) (*config.Config, error) {
+	cfg, err := config.LoadUser()
+	if err != nil {
+		if create && errors.Is(err, fs.ErrNotExist) {
+			return &config.Config{
+				Settings:  map[string]interface{}{},
+				Providers: map[string]map[string]interface{}{},
+			}, nil
+		}
+		return nil, err
+	}
+	if cfg.Providers == nil {
+		cfg.Providers = map[string]map[string]interface{}{}
+	}
+	return cfg, nil
+}
+
+// brokenProviders returns the providers that fail config.CheckProvider.
+func brokenProviders(cfg *config.Config) map[string]error {
+	broken := make(map[string]error)
+	for name := range cfg.Providers {
+		if err := config.CheckProvider(cfg, name); err != nil {
+			broken[name] = err
+		}
+	}
+	return broken
+}
+
+// runProvider executes the provider command. The modified config is only
+// saved if the change does not break any provider that was valid before.
+// The change is first tried on a snapshot of ccc.json, so that usage errors
+// and the token prompt happen without holding the config lock, and then
+// applied again in a locked load-modify-save.
+func runProvider(opts *ProviderCommand) error {
+	if opts.Err != nil {
+		return fmt.Errorf("%v\n%s", opts.Err, providerUsage)
+	}
+
+	cfg, err := loadUserConfigForEdit(opts.Action == "add")
+	if err != nil {
+		return err
+	}
+
+	if opts.Action == "get" {
+		return providerGet(os.Stdout, cfg, opts.Args)
+	}
+
+	if _, err := applyProviderAction(cfg, opts); err != nil {
+		return err
+	}
+	var message string
+	err = config.UpdateConfig(func(cfg *config.Config) error {
+		var err error
+		message, err = applyProviderAction(cfg, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println(message)
+	return nil
+}
+
+// applyProviderAction applies a modifying provider command to cfg and
+// returns the message to print. It fails if the change breaks a provider
+// that was valid before.
+func applyProviderAction(cfg *config.Config, opts *ProviderCommand) (string, error) {
+	before := brokenProviders(cfg)
+	var message string
+	var err error
+	switch opts.Action {
+	case "add":
+		message, err = providerAdd(cfg, opts)
+	case "rm":
+		message, err = providerRemove(cfg, opts.Args)
+	case "mv":
+		message, err = providerRename(cfg, opts.Args)
+	case "cp":
+		message, err = providerCopy(cfg, opts.Args)
+	case "set":
+		message, err = providerSet(cfg, opts)
+	case "":
+		return "", fmt.Errorf("%s", providerUsage)
+	default:
+		return "", fmt.Errorf("unknown provider command '%s'\n%s", opts.Action, providerUsage)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	after := brokenProviders(cfg)
+	for _, name := range cfg.ProviderNames() {
+		if _, wasBroken := before[name]; after[name] != nil && !wasBroken {
+			return "", fmt.Errorf("not saved: %w", after[name])
+		}
+	}
+	return message, nil
+}
+
+// providerAdd adds a new provider built from the add flags. With --preset,
+// the preset supplies the base URL, models and headers, the name defaults to
+// the preset name, and explicit flags override the preset values. The token
+// is stored in the preset's TokenEnv.
+func providerAdd(cfg *config.Config, opts *ProviderCommand) (string, error) {
+	env := make(map[string]interface{})
+	name := ""
+	tokenEnv := "ANTHROPIC_AUTH_TOKEN"
+	if len(opts.Args) == 1 {
+		name = opts.Args[0]
+	}
+	if opts.Preset != "" {
+		p, err := preset.Find(opts.Preset)
+		if err != nil {
+			return "", err
+		}
+		env = p.Env()
+		tokenEnv = p.TokenEnv()
+		if len(opts.Args) == 0 {
+			name = p.Name
+		}
+	}
+	if len(opts.Args) > 1 || name == "" {
+		return "", fmt.Errorf("usage: ccc provider add <name> --base-url <url> [--model <model>] [--token <token>]")
+	}
+	if err := checkProviderName(name); err != nil {
+		return "", err
+	}
+	if _, exists := cfg.Providers[name]; exists {
+		return "", fmt.Errorf("provider '%s' already exists", name)
+	}
+	if opts.BaseURL == "" && opts.Extends == "" && opts.Preset == "" {
+		return "", fmt.Errorf("--base-url is required (or --preset, or --extends to inherit it)")
+	}
+
+	token := opts.Token
+	if token == "" && opts.Extends == "" {
+		var err error
+		token, err = ReadSecretFunc(fmt.Sprintf("%s for %s: ", tokenEnv, name))
+		if err != nil {
+			return "", fmt.Errorf("failed to read token: %w", err)
+		}
+		if token == "" {
+			return "", fmt.Errorf("a token is required (use --token, or a secret reference such as env:NAME)")
+		}
+		// Remember the token, runProvider applies the command twice
+		opts.Token = token
+	}
+
+	for key, value := range map[string]string{
+		"ANTHROPIC_BASE_URL":         opts.BaseURL,
+		tokenEnv:                     token,
+		"ANTHROPIC_MODEL":            opts.Model,
+		"ANTHROPIC_SMALL_FAST_MODEL": opts.SmallFastModel,
+	} {
+		if value != "" {
+			env[key] = value
+		}
+	}
+
+	p := make(map[string]interface{})
+	if opts.Extends != "" {
+		p["extends"] = opts.Extends
+	}
+	if len(env) > 0 {
+		p["env"] = env
+	}
+	cfg.Providers[name] = p
+	if cfg.CurrentProvider == "" {
+		cfg.CurrentProvider = name
+	}
+	return fmt.Sprintf("Added provider '%s'", name), nil
+}
+
+// providerRemove removes a provider. If it was the current provider,
+// the default provider (see provider.GetDefaultProvider) becomes current.
+func providerRemove(cfg *config.Config, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("usage: ccc provider rm <name>")
+	}
+	name, err := provider.Resolve(cfg, args[0])
+	if err != nil {
+		return "", err
+	}
+	delete(cfg.Providers, name)
+	if cfg.DefaultProvider == name {
+		cfg.DefaultProvider = ""
+	}
+	for alias, target := range cfg.Aliases {
+		if target == name {
+			delete(cfg.Aliases, alias)
+		}
+	}
+
+	message := fmt.Sprintf("Removed provider '%s'", name)
+	if cfg.CurrentProvider == name {
+		cfg.CurrentProvider = provider.GetDefaultProvider(cfg)
+		if cfg.CurrentProvider != "" {
+			message += fmt.Sprintf(", current provider is now '%s'", cfg.CurrentProvider)
+		}
+	}
+	return message, nil
+}
+
+// providerRename renames a provider and updates current_provider and
+// the extends/failover references of other providers.
+func providerRename(cfg *config.Config, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("usage: ccc provider mv <old> <new>")
+	}
+	oldName, err := provider.Resolve(cfg, args[0])
+	if err != nil {
+		return "", err
+	}
+	newName := args[1]
+	if err := checkProviderName(newName); err != nil {
+		return "", err
+	}
+	if _, exists := cfg.Providers[newName]; exists {
+		return "", fmt.Errorf("provider '%s' already exists", newName)
+	}
+
+	cfg.RenameProvider(oldName, newName)
+	if cfg.CurrentProvider == oldName {
+		cfg.CurrentProvider = newName
+	}
+	if cfg.DefaultProvider == oldName {
+		cfg.DefaultProvider = newName
+	}
+	for _, other := range cfg.Providers {
+		if other["extends"] == oldName {
+			other["extends"] = newName
+		}
+		if members, ok := other["failover"].([]interface{}); ok {
+			for i, m := range members {
+				if m == oldName {
+					members[i] = newName
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("Renamed provider '%s' to '%s'", oldName, newName), nil
+}
+
+// providerCopy copies a provider under a new name.
+func providerCopy(cfg *config.Config, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("usage: ccc provider cp <src> <dst>")
+	}
+	src, err := provider.Resolve(cfg, args[0])
+	if err != nil {
+		return "", err
+	}
+	dst := args[1]
+	p := cfg.Providers[src]
+	if err := checkProviderName(dst); err != nil {
+		return "", err
+	}
+	if _, exists := cfg.Providers[dst]; exists {
+		return "", fmt.Errorf("provider '%s' already exists", dst)
+	}
+
+	copied := config.DeepCopy(p)
+	if copied == nil {
+		copied = map[string]interface{}{}
+	}
+	// Aliases name a single provider, the copy would claim them too
+	delete(copied, "aliases")
+	cfg.Providers[dst] = copied
+	return fmt.Sprintf("Copied provider '%s' to '%s'", src, dst), nil
+}
+
+// providerSet sets or removes a value at a dotted path of a provider.
+func providerSet(cfg *config.Config, opts *ProviderCommand) (string, error) {
+	args := opts.Args
+	if (opts.Unset && len(args) != 2) || (!opts.Unset && len(args) != 3) {
+		return "", fmt.Errorf("usage: ccc provider set <name> <path> <value> | ccc provider set <name> <path> --unset")
+	}
+	name, err := provider.Resolve(cfg, args[0])
+	if err != nil {
+		return "", err
+	}
+	path := args[1]
+	p := cfg.Providers[name]
+	if p == nil {
+		p = map[string]interface{}{}
+		cfg.Providers[name] = p
+	}
+
+	if opts.Unset {
+		if !config.DeletePath(p, path) {
+			return "", fmt.Errorf("provider '%s' has no value at '%s'", name, path)
+		}
+		return fmt.Sprintf("Removed %s from provider '%s'", path, name), nil
+	}
+
+	var value interface{} = args[2]
+	if opts.JSON {
+		if err := json.Unmarshal([]byte(args[2]), &value); err != nil {
+			return "", fmt.Errorf("invalid JSON value: %w", err)
+		}
+	}
+	if err := config.SetPath(p, path, value); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Set %s for provider '%s'", path, name), nil
+}
+
+// providerGet prints a provider, or the value at a dotted path of it.
+// Strings are printed as-is, other values as JSON.
+func providerGet(w io.Writer, cfg *config.Config, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: ccc provider get <name> [<path>]")
+	}
+	name, err := provider.Resolve(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	p := cfg.Providers[name]
+
+	var value interface{} = p
+	if len(args) == 2 {
+		v, ok := config.GetPath(p, args[1])
+		if !ok {
+			return fmt.Errorf("provider '%s' has no value at '%s'", name, args[1])
+		}
+		value = v
+	}
+
+	if s, ok := value.(string); ok {
+		fmt.Fprintln(w, s)
+		return nil
+	}
+	data, err := prettyjson.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return nil
+}
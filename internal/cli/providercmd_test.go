@@ -0,0 +1,363 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// writeTestConfig saves a ccc.json with the given providers into the test config dir.
+func writeTestConfig(t *testing.T, current string, providers map[string]map[string]interface{}) {
+	t.Helper()
+	cfg := &config.Config{
+		Settings:        map[string]interface{}{},
+		CurrentProvider: current,
+		Providers:       providers,
+	}
+	if err := config.Save(cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+}
+
+// runProviderArgs parses and runs a provider command line.
+func runProviderArgs(t *testing.T, args ...string) error {
+	t.Helper()
+	cmd := Parse(append([]string{"provider"}, args...))
+	if !cmd.ProviderCmd {
+		t.Fatal("ProviderCmd = false, want true")
+	}
+	return runProvider(cmd.ProviderOpts)
+}
+
+func TestParseProviderArgs(t *testing.T) {
+	opts := parseProviderArgs([]string{"add", "kimi", "--base-url", "https://api.moonshot.cn/anthropic", "--model", "kimi-k2"})
+	if opts.Err != nil {
+		t.Fatalf("unexpected error: %v", opts.Err)
+	}
+	if opts.Action != "add" || strings.Join(opts.Args, ",") != "kimi" {
+		t.Errorf("Action = %q, Args = %v", opts.Action, opts.Args)
+	}
+	if opts.BaseURL != "https://api.moonshot.cn/anthropic" || opts.Model != "kimi-k2" {
+		t.Errorf("BaseURL = %q, Model = %q", opts.BaseURL, opts.Model)
+	}
+
+	opts = parseProviderArgs([]string{"set", "kimi", "--json", "env.X", "--", "-1"})
+	if !opts.JSON || strings.Join(opts.Args, ",") != "kimi,env.X,-1" {
+		t.Errorf("JSON = %v, Args = %v", opts.JSON, opts.Args)
+	}
+
+	if opts := parseProviderArgs([]string{"add", "--bogus"}); opts.Err == nil {
+		t.Error("unknown flag should be reported")
+	}
+}
+
+func TestProviderAdd(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	originalRead := ReadSecretFunc
+	defer func() { ReadSecretFunc = originalRead }()
+	ReadSecretFunc = func(prompt string) (string, error) { return "sk-prompted", nil }
+
+	// Creates ccc.json when missing, and the first provider becomes current
+	if err := runProviderArgs(t, "add", "kimi", "--base-url", "https://api.moonshot.cn/anthropic", "--model", "kimi-k2"); err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	cfg, err := config.LoadUser()
+	if err != nil {
+		t.Fatalf("LoadUser() error = %v", err)
+	}
+	if cfg.CurrentProvider != "kimi" {
+		t.Errorf("CurrentProvider = %q, want kimi", cfg.CurrentProvider)
+	}
+	env := config.GetEnv(cfg.Providers["kimi"])
+	if env["ANTHROPIC_AUTH_TOKEN"] != "sk-prompted" || env["ANTHROPIC_MODEL"] != "kimi-k2" {
+		t.Errorf("env = %v", env)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"duplicate", []string{"add", "kimi", "--base-url", "https://a.example.com", "--token", "x"}},
+		{"reserved name", []string{"add", "validate", "--base-url", "https://a.example.com", "--token", "x"}},
+		{"missing base url", []string{"add", "glm", "--token", "x"}},
+		{"invalid base url", []string{"add", "glm", "--base-url", "ftp://a.example.com", "--token", "x"}},
+		{"unknown parent", []string{"add", "glm", "--extends", "missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runProviderArgs(t, tt.args...); err == nil {
+				t.Error("expected error")
+			}
+			cfg, _ := config.LoadUser()
+			if len(cfg.Providers) != 1 {
+				t.Errorf("config should be unchanged, got providers %v", cfg.Providers)
+			}
+		})
+	}
+
+	t.Run("empty prompted token", func(t *testing.T) {
+		ReadSecretFunc = func(prompt string) (string, error) { return "", nil }
+		if err := runProviderArgs(t, "add", "glm", "--base-url", "https://open.bigmodel.cn/api/anthropic"); err == nil {
+			t.Error("expected error for empty token")
+		}
+	})
+}
+
+func TestProviderRemoveRenameCopy(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	writeTestConfig(t, "kimi", map[string]map[string]interface{}{
+		"kimi": {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://api.moonshot.cn/anthropic"}},
+		"glm":  {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://open.bigmodel.cn/api/anthropic"}},
+		"kimi-fast": {
+			"extends": "kimi",
+			"env":     map[string]interface{}{"ANTHROPIC_MODEL": "kimi-k2-turbo"},
+		},
+		"auto": {"failover": []interface{}{"kimi", "glm"}},
+	})
+
+	// Removing a provider others depend on is refused
+	if err := runProviderArgs(t, "rm", "kimi"); err == nil || !strings.Contains(err.Error(), "not saved") {
+		t.Errorf("rm kimi error = %v, want not saved", err)
+	}
+
+	// Renaming updates current_provider, extends and failover references
+	if err := runProviderArgs(t, "mv", "kimi", "moonshot"); err != nil {
+		t.Fatalf("mv error = %v", err)
+	}
+	cfg, _ := config.LoadUser()
+	if cfg.CurrentProvider != "moonshot" {
+		t.Errorf("CurrentProvider = %q, want moonshot", cfg.CurrentProvider)
+	}
+	if cfg.Providers["kimi-fast"]["extends"] != "moonshot" {
+		t.Errorf("extends = %v, want moonshot", cfg.Providers["kimi-fast"]["extends"])
+	}
+	if members := config.GetFailover(cfg.Providers["auto"]); strings.Join(members, ",") != "moonshot,glm" {
+		t.Errorf("failover = %v, want [moonshot glm]", members)
+	}
+
+	// Copies are independent
+	if err := runProviderArgs(t, "cp", "glm", "glm-copy"); err != nil {
+		t.Fatalf("cp error = %v", err)
+	}
+	if err := runProviderArgs(t, "set", "glm-copy", "env.ANTHROPIC_MODEL", "glm-4.7"); err != nil {
+		t.Fatalf("set error = %v", err)
+	}
+	cfg, _ = config.LoadUser()
+	if config.GetModel(cfg.Providers["glm"]) != "" {
+		t.Error("changing a copy must not change the original")
+	}
+
+	// Removing the current provider selects the first remaining one
+	if err := runProviderArgs(t, "mv", "moonshot", "kimi"); err != nil {
+		t.Fatalf("mv error = %v", err)
+	}
+	cfg, _ = config.LoadUser()
+	cfg.Aliases = map[string]string{"k": "kimi", "z": "glm", "kf": "kimi-fast"}
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := runProviderArgs(t, "rm", "auto"); err != nil {
+		t.Fatalf("rm auto error = %v", err)
+	}
+	// Aliases name the provider to copy, rename or remove
+	if err := runProviderArgs(t, "cp", "kf", "kimi-faster"); err != nil {
+		t.Fatalf("cp kf error = %v", err)
+	}
+	if err := runProviderArgs(t, "mv", "kimi-faster", "kimi-fastest"); err != nil {
+		t.Fatalf("mv error = %v", err)
+	}
+	if err := runProviderArgs(t, "rm", "kimi-fastest"); err != nil {
+		t.Fatalf("rm kimi-fastest error = %v", err)
+	}
+	if err := runProviderArgs(t, "mv", "kf", "kimi-turbo"); err != nil {
+		t.Fatalf("mv kf error = %v", err)
+	}
+	if cfg, _ = config.LoadUser(); cfg.Aliases["kf"] != "kimi-turbo" {
+		t.Errorf("Aliases = %v, want kf renamed along", cfg.Aliases)
+	}
+	if err := runProviderArgs(t, "rm", "kf"); err != nil {
+		t.Fatalf("rm kf error = %v", err)
+	}
+	if err := runProviderArgs(t, "rm", "kimi"); err != nil {
+		t.Fatalf("rm kimi error = %v", err)
+	}
+	cfg, _ = config.LoadUser()
+	if cfg.CurrentProvider != "glm" {
+		t.Errorf("CurrentProvider = %q, want glm", cfg.CurrentProvider)
+	}
+	// Top-level aliases of a removed provider are removed with it
+	if _, exists := cfg.Providers["kimi-turbo"]; exists {
+		t.Error("rm kf should remove kimi-turbo")
+	}
+	if !reflect.DeepEqual(cfg.Aliases, map[string]string{"z": "glm"}) {
+		t.Errorf("Aliases = %v, want only z", cfg.Aliases)
+	}
+
+	for _, args := range [][]string{
+		{"mv", "missing", "x"},
+		{"mv", "glm", "glm-copy"},
+		{"cp", "glm", "-x"},
+		{"rm", "missing"},
+		{"frobnicate"},
+		{},
+	} {
+		if err := runProviderArgs(t, args...); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestProviderSetGet(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	writeTestConfig(t, "kimi", map[string]map[string]interface{}{
+		"kimi": {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://api.moonshot.cn/anthropic"}},
+	})
+
+	if err := runProviderArgs(t, "set", "kimi", "env.ANTHROPIC_MODEL", "kimi-k2"); err != nil {
+		t.Fatalf("set error = %v", err)
+	}
+	if err := runProviderArgs(t, "set", "kimi", "alwaysThinkingEnabled", "true", "--json"); err != nil {
+		t.Fatalf("set --json error = %v", err)
+	}
+	if err := runProviderArgs(t, "set", "kimi", "env.ANTHROPIC_BASE_URL", "not a url"); err == nil {
+		t.Error("set should refuse an invalid base URL")
+	}
+	if err := runProviderArgs(t, "set", "kimi", "env.ANTHROPIC_MODEL.x", "y"); err == nil {
+		t.Error("set should refuse to descend into a string")
+	}
+	if err := runProviderArgs(t, "set", "kimi", "x", "{", "--json"); err == nil {
+		t.Error("set should refuse invalid JSON")
+	}
+
+	cfg, _ := config.LoadUser()
+	var out bytes.Buffer
+	if err := providerGet(&out, cfg, []string{"kimi", "env.ANTHROPIC_MODEL"}); err != nil {
+		t.Fatalf("get error = %v", err)
+	}
+	if out.String() != "kimi-k2\n" {
+		t.Errorf("get = %q, want kimi-k2", out.String())
+	}
+	out.Reset()
+	if err := providerGet(&out, cfg, []string{"kimi", "alwaysThinkingEnabled"}); err != nil || out.String() != "true\n" {
+		t.Errorf("get = %q, %v, want true", out.String(), err)
+	}
+	out.Reset()
+	if err := providerGet(&out, cfg, []string{"kimi"}); err != nil || !strings.Contains(out.String(), `"ANTHROPIC_MODEL": "kimi-k2"`) {
+		t.Errorf("get = %q, %v", out.String(), err)
+	}
+	if err := providerGet(&out, cfg, []string{"kimi", "env.MISSING"}); err == nil {
+		t.Error("get should fail for a missing path")
+	}
+
+	// Aliases name the provider
+	cfg.Aliases = map[string]string{"moon": "kimi"}
+	if err := config.Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := runProviderArgs(t, "set", "moon", "env.ANTHROPIC_SMALL_FAST_MODEL", "kimi-k2-turbo"); err != nil {
+		t.Fatalf("set moon error = %v", err)
+	}
+	cfg, _ = config.LoadUser()
+	out.Reset()
+	if err := providerGet(&out, cfg, []string{"moon", "env.ANTHROPIC_SMALL_FAST_MODEL"}); err != nil || out.String() != "kimi-k2-turbo\n" {
+		t.Errorf("get moon = %q, %v, want kimi-k2-turbo", out.String(), err)
+	}
+	if _, exists := cfg.Providers["moon"]; exists {
+		t.Error("set with an alias must not create a provider")
+	}
+
+	if err := runProviderArgs(t, "set", "kimi", "env.ANTHROPIC_MODEL", "--unset"); err != nil {
+		t.Fatalf("set --unset error = %v", err)
+	}
+	cfg, _ = config.LoadUser()
+	if config.GetModel(cfg.Providers["kimi"]) != "" {
+		t.Error("ANTHROPIC_MODEL should be removed")
+	}
+}
+
+func TestProviderAddPreset(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	originalRead := ReadSecretFunc
+	defer func() { ReadSecretFunc = originalRead }()
+	ReadSecretFunc = func(prompt string) (string, error) { return "sk-prompted", nil }
+
+	// The name defaults to the preset name
+	if err := runProviderArgs(t, "add", "--preset", "kimi"); err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	// Explicit flags override preset values
+	if err := runProviderArgs(t, "add", "work", "--preset", "kimi", "--model", "kimi-k2", "--token", "sk-work"); err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	cfg, err := config.LoadUser()
+	if err != nil {
+		t.Fatalf("LoadUser() error = %v", err)
+	}
+
+	env := config.GetEnv(cfg.Providers["kimi"])
+	if env["ANTHROPIC_BASE_URL"] != "https://api.moonshot.cn/anthropic" || env["ANTHROPIC_AUTH_TOKEN"] != "sk-prompted" {
+		t.Errorf("kimi env = %v", env)
+	}
+	if env["ANTHROPIC_MODEL"] == nil || env["ANTHROPIC_SMALL_FAST_MODEL"] == nil {
+		t.Errorf("kimi env = %v, want preset models", env)
+	}
+	env = config.GetEnv(cfg.Providers["work"])
+	if env["ANTHROPIC_MODEL"] != "kimi-k2" || env["ANTHROPIC_AUTH_TOKEN"] != "sk-work" {
+		t.Errorf("work env = %v", env)
+	}
+
+	// The anthropic preset stores the token as ANTHROPIC_API_KEY (x-api-key)
+	var prompt string
+	ReadSecretFunc = func(p string) (string, error) { prompt = p; return "sk-ant", nil }
+	if err := runProviderArgs(t, "add", "--preset", "anthropic"); err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	if !strings.HasPrefix(prompt, "ANTHROPIC_API_KEY") {
+		t.Errorf("prompt = %q, want ANTHROPIC_API_KEY", prompt)
+	}
+	cfg, _ = config.LoadUser()
+	env = config.GetEnv(cfg.Providers["anthropic"])
+	if env["ANTHROPIC_API_KEY"] != "sk-ant" || env["ANTHROPIC_AUTH_TOKEN"] != nil {
+		t.Errorf("anthropic env = %v, want ANTHROPIC_API_KEY only", env)
+	}
+
+	if err := runProviderArgs(t, "add", "--preset", "nope"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown preset error = %v", err)
+	}
+}
+
+func TestRunPresets(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	if err := runPresets(&buf, parsePresetsArgs([]string{"list"})); err != nil {
+		t.Fatalf("runPresets() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "NAME") || !strings.Contains(buf.String(), "https://api.moonshot.cn/anthropic") {
+		t.Errorf("output = %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := runPresets(&buf, parsePresetsArgs([]string{"--json"})); err != nil {
+		t.Fatalf("runPresets(--json) error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"name": "kimi"`) || !strings.Contains(buf.String(), `"source": "builtin"`) {
+		t.Errorf("JSON output = %q", buf.String())
+	}
+
+	if err := runPresets(&buf, parsePresetsArgs([]string{"show"})); err == nil {
+		t.Error("unknown presets command should fail")
+	}
+}
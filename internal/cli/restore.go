@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// runRestore lists backups or rolls ccc.json or settings.json back to one.
+// It runs before the config is loaded, so a broken ccc.json can be restored.
+func runRestore(opts *RestoreCommand) error {
+	if opts.List {
+		backups, err := config.ListBackups()
+		if err != nil {
+			return err
+		}
+		printBackups(os.Stdout, backups)
+		return nil
+	}
+
+	backup, err := config.FindBackup(opts.ID)
+	if err != nil {
+		return err
+	}
+	if err := config.RestoreBackup(backup); err != nil {
+		return err
+	}
+	fmt.Printf("Restored %s from backup %s\n", backup.File, backup.ID)
+	fmt.Println("The replaced version was backed up, run 'ccc restore --list' to see it")
+	return nil
+}
+
+// printBackups prints backups newest first.
+func printBackups(w io.Writer, backups []config.Backup) {
+	if len(backups) == 0 {
+		fmt.Fprintf(w, "No backups found in %s\n", config.GetBackupDir())
+		return
+	}
+	fmt.Fprintf(w, "Backups in %s (newest first):\n", config.GetBackupDir())
+	for _, b := range backups {
+		fmt.Fprintf(w, "  %s  %-13s  %s\n", b.ID, b.File, b.Time.Format("2006-01-02 15:04:05"))
+	}
+}
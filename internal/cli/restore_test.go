@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func TestParseRestoreArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantList bool
+		wantID   string
+	}{
+		{"no args", []string{}, false, ""},
+		{"--list", []string{"--list"}, true, ""},
+		{"id", []string{"20260101-120000"}, false, "20260101-120000"},
+		{"file name", []string{"settings.json"}, false, "settings.json"},
+		{"unknown flag lists", []string{"--bogus"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Parse(append([]string{"restore"}, tt.args...))
+			if !cmd.Restore {
+				t.Fatal("Restore = false, want true")
+			}
+			if cmd.RestoreOpts.List != tt.wantList {
+				t.Errorf("List = %v, want %v", cmd.RestoreOpts.List, tt.wantList)
+			}
+			if cmd.RestoreOpts.ID != tt.wantID {
+				t.Errorf("ID = %q, want %q", cmd.RestoreOpts.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestRunRestore(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := runRestore(&RestoreCommand{}); err == nil {
+		t.Error("runRestore() should fail without backups")
+	}
+
+	if err := config.SaveSettings(map[string]interface{}{"model": "opus"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SaveSettings(map[string]interface{}{"model": "sonnet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restore works even when ccc.json is broken
+	if err := os.WriteFile(config.GetConfigPath(), []byte(`{broken`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(&Command{Restore: true, RestoreOpts: &RestoreCommand{ID: "settings.json"}}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings["model"] != "opus" {
+		t.Errorf("model = %v, want opus", settings["model"])
+	}
+}
+
+func TestPrintBackups(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+
+	var buf bytes.Buffer
+	printBackups(&buf, nil)
+	if !strings.Contains(buf.String(), "No backups found") {
+		t.Errorf("output = %q, want no backups message", buf.String())
+	}
+
+	buf.Reset()
+	printBackups(&buf, []config.Backup{{ID: "20260101-120000.000000-ccc.json", File: "ccc.json"}})
+	if !strings.Contains(buf.String(), "20260101-120000.000000-ccc.json") {
+		t.Errorf("output = %q, want backup ID", buf.String())
+	}
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// TrustCommand represents options for the trust command.
+type TrustCommand struct {
+	Path string // Project config to trust; empty means the nearest .ccc.json
+}
+
+// runTrust trusts the current content of a project config, so that it is
+// merged over ccc.json until it changes.
+func runTrust(w io.Writer, opts *TrustCommand) error {
+	path := opts.Path
+	if path == "" {
+		if path = config.FindProjectConfig(); path == "" {
+			return fmt.Errorf("no %s found in the working directory or its parents", config.ProjectConfigName)
+		}
+	}
+	if err := config.TrustProject(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Trusted %s\n", path)
+	fmt.Fprintln(w, "It is ignored again if it changes, run 'ccc trust' after reviewing the changes")
+	return nil
+}
+
+// warnUntrustedProject tells the user that a project config was ignored.
+func warnUntrustedProject(w io.Writer, cfg *config.Config) {
+	if cfg.UntrustedProject == "" {
+		return
+	}
+	fmt.Fprintf(w, "Warning: ignoring %s, which is not trusted or has changed since\n", cfg.UntrustedProject)
+	fmt.Fprintln(w, "Review it, then run 'ccc trust' to use it")
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func TestRunTrust(t *testing.T) {
+	cleanup := setupTestDir(t)
+	defer cleanup()
+	writeTestConfig(t, "glm", map[string]map[string]interface{}{"glm": {}, "kimi": {}})
+
+	projectDir := t.TempDir()
+	originalWorkDir := config.GetWorkDirFunc
+	config.GetWorkDirFunc = func() (string, error) { return projectDir, nil }
+	defer func() { config.GetWorkDirFunc = originalWorkDir }()
+
+	if cmd := Parse([]string{"trust"}); !cmd.Trust || cmd.TrustOpts.Path != "" {
+		t.Errorf("Parse(trust) = %+v", cmd)
+	}
+	var out bytes.Buffer
+	if err := runTrust(&out, &TrustCommand{}); err == nil {
+		t.Error("runTrust() should fail without a project config")
+	}
+
+	projectPath := filepath.Join(projectDir, config.ProjectConfigName)
+	if err := os.WriteFile(projectPath, []byte(`{"provider": "kimi"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	warnUntrustedProject(&out, cfg)
+	if cfg.CurrentProvider != "glm" || !strings.Contains(out.String(), "ccc trust") {
+		t.Errorf("untrusted project config: current = %q, warning %q", cfg.CurrentProvider, out.String())
+	}
+
+	out.Reset()
+	if err := runTrust(&out, &TrustCommand{}); err != nil {
+		t.Fatalf("runTrust() error = %v", err)
+	}
+	if !strings.Contains(out.String(), projectPath) {
+		t.Errorf("runTrust() output = %q, want the trusted path", out.String())
+	}
+	if cfg, err = config.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	out.Reset()
+	warnUntrustedProject(&out, cfg)
+	if cfg.CurrentProvider != "kimi" || out.Len() > 0 {
+		t.Errorf("trusted project config: current = %q, warning %q", cfg.CurrentProvider, out.String())
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GetAliases returns the aliases of a provider: the names in its own
+// `aliases` list, e.g. {"aliases": ["k", "kimi-prod"]}, and the entries of
+// the top-level alias map pointing to it, sorted and without duplicates.
+// Aliases are not inherited through extends. Entries that are not strings
+// are ignored, CheckAliases reports them.
+func GetAliases(cfg *Config, name string) []string {
+	seen := make(map[string]bool)
+	if list, ok := cfg.Providers[name]["aliases"].([]interface{}); ok {
+		for _, v := range list {
+			if alias, ok := v.(string); ok {
+				seen[alias] = true
+			}
+		}
+	}
+	for alias, target := range cfg.Aliases {
+		if target == name {
+			seen[alias] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+
+	aliases := make([]string, 0, len(seen))
+	for alias := range seen {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// ResolveAlias returns the provider that name refers to: name itself if it
+// is a provider name, which always wins over aliases, or the provider it is
+// an alias of. Unknown names and invalid aliases are returned unchanged. An alias claimed by
+// several providers resolves through the top-level alias map first, then
+// to the first of them in declaration order.
+func ResolveAlias(cfg *Config, name string) string {
+	if isProvider(cfg, name) || !isValidAlias(name) {
+		return name
+	}
+	if target, ok := cfg.Aliases[name]; ok && isProvider(cfg, target) {
+		return target
+	}
+	for _, provider := range cfg.ProviderNames() {
+		for _, alias := range GetAliases(cfg, provider) {
+			if alias == name {
+				return provider
+			}
+		}
+	}
+	return name
+}
+
+// AllAliases returns every alias that resolves to an existing provider, sorted.
+func AllAliases(cfg *Config) []string {
+	var aliases []string
+	seen := make(map[string]bool)
+	for _, provider := range cfg.ProviderNames() {
+		for _, alias := range GetAliases(cfg, provider) {
+			if !seen[alias] && ResolveAlias(cfg, alias) != alias {
+				seen[alias] = true
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// CheckAliases returns the problems with the names of a provider: a provider
+// name that is one of the reserved subcommand names (the subcommand shadows
+// it on the command line), a malformed `aliases` list, and aliases that are
+// empty, start with "-", collide with a provider name or one of the reserved
+// subcommand names, or are claimed by another provider too. With an empty
+// name, it returns the problems of the top-level alias map that belong to no
+// provider: aliases of providers that do not exist.
+func CheckAliases(cfg *Config, name string, reserved []string) []string {
+	var problems []string
+	if name == "" {
+		for alias, target := range cfg.Aliases {
+			if !isProvider(cfg, target) {
+				problems = append(problems, fmt.Sprintf("alias '%s' refers to missing provider '%s'", alias, target))
+			}
+		}
+		sort.Strings(problems)
+		return problems
+	}
+
+	for _, r := range reserved {
+		if name == r {
+			problems = append(problems, fmt.Sprintf("provider name '%s' collides with the '%s' command, rename it with 'ccc provider mv %s <new-name>'", name, r, name))
+		}
+	}
+
+	if raw, ok := cfg.Providers[name]["aliases"]; ok {
+		list, ok := raw.([]interface{})
+		if !ok {
+			problems = append(problems, "aliases must be a list of names")
+		}
+		for _, v := range list {
+			if _, ok := v.(string); !ok {
+				problems = append(problems, fmt.Sprintf("alias %v must be a string", v))
+			}
+		}
+	}
+
+	for _, alias := range GetAliases(cfg, name) {
+		switch {
+		case !isValidAlias(alias):
+			problems = append(problems, fmt.Sprintf("invalid alias '%s'", alias))
+			continue
+		case isProvider(cfg, alias):
+			problems = append(problems, fmt.Sprintf("alias '%s' collides with provider '%s'", alias, alias))
+			continue
+		}
+		for _, r := range reserved {
+			if alias == r {
+				problems = append(problems, fmt.Sprintf("alias '%s' collides with the '%s' command", alias, r))
+			}
+		}
+		for _, other := range cfg.ProviderNames() {
+			if other == name {
+				continue
+			}
+			for _, a := range GetAliases(cfg, other) {
+				if a == alias {
+					problems = append(problems, fmt.Sprintf("alias '%s' is also an alias of provider '%s'", alias, other))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// isValidAlias reports whether alias can be used on the command line.
+func isValidAlias(alias string) bool {
+	return alias != "" && !strings.HasPrefix(alias, "-")
+}
+
+// isProvider reports whether name is a provider name.
+func isProvider(cfg *Config, name string) bool {
+	_, exists := cfg.Providers[name]
+	return exists
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	cfg := &Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi":       {"aliases": []interface{}{"k", "kimi-prod"}},
+			"kimi-turbo": {"extends": "kimi"},
+			"glm":        {"aliases": []interface{}{"g", "kimi"}},
+			"minimax":    {"aliases": []interface{}{"m", "validate", "", 1.0}},
+			"minimax2":   {"aliases": []interface{}{"m"}},
+			"patch":      {},
+		},
+		Aliases: map[string]string{"zhipu": "glm", "g": "glm", "gone": "deleted"},
+	}
+
+	t.Run("GetAliases", func(t *testing.T) {
+		tests := []struct {
+			name string
+			want []string
+		}{
+			{"kimi", []string{"k", "kimi-prod"}},
+			{"kimi-turbo", nil}, // Aliases are not inherited
+			{"glm", []string{"g", "kimi", "zhipu"}},
+			{"missing", nil},
+		}
+		for _, tt := range tests {
+			if got := GetAliases(cfg, tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetAliases(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("ResolveAlias", func(t *testing.T) {
+		tests := []struct {
+			name string
+			want string
+		}{
+			{"kimi", "kimi"}, // Provider names win over aliases
+			{"k", "kimi"},
+			{"kimi-prod", "kimi"},
+			{"zhipu", "glm"},
+			{"m", "minimax"},
+			{"gone", "gone"},
+			{"unknown", "unknown"},
+		}
+		for _, tt := range tests {
+			if got := ResolveAlias(cfg, tt.name); got != tt.want {
+				t.Errorf("ResolveAlias(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("AllAliases", func(t *testing.T) {
+		want := []string{"g", "k", "kimi-prod", "m", "validate", "zhipu"}
+		if got := AllAliases(cfg); !reflect.DeepEqual(got, want) {
+			t.Errorf("AllAliases() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("CheckAliases", func(t *testing.T) {
+		reserved := []string{"validate", "patch"}
+		tests := []struct {
+			name string
+			want []string
+		}{
+			{"kimi", nil},
+			{"glm", []string{"alias 'kimi' collides with provider 'kimi'"}},
+			{"minimax", []string{
+				"alias 1 must be a string",
+				"invalid alias ''",
+				"alias 'm' is also an alias of provider 'minimax2'",
+				"alias 'validate' collides with the 'validate' command",
+			}},
+			{"minimax2", []string{"alias 'm' is also an alias of provider 'minimax'"}},
+			{"patch", []string{"provider name 'patch' collides with the 'patch' command, rename it with 'ccc provider mv patch <new-name>'"}},
+		}
+		for _, tt := range tests {
+			if got := CheckAliases(cfg, tt.name, reserved); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckAliases(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		}
+
+		// Top-level aliases of missing providers belong to no provider
+		want := []string{"alias 'gone' refers to missing provider 'deleted'"}
+		if got := CheckAliases(cfg, "", reserved); !reflect.DeepEqual(got, want) {
+			t.Errorf("CheckAliases(\"\") = %q, want %q", got, want)
+		}
+
+		malformed := &Config{Providers: map[string]map[string]interface{}{"kimi": {"aliases": "k"}}}
+		want = []string{"aliases must be a list of names"}
+		if got := CheckAliases(malformed, "kimi", reserved); !reflect.DeepEqual(got, want) {
+			t.Errorf("CheckAliases() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestProviderSettingsStripsAliases(t *testing.T) {
+	got := ProviderSettings(map[string]interface{}{"aliases": []interface{}{"k"}, "env": map[string]interface{}{}})
+	if _, ok := got["aliases"]; ok {
+		t.Errorf("ProviderSettings() kept aliases: %v", got)
+	}
+}
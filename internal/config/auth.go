@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guyskk/ccc/internal/secret"
+)
+
+// RunAPIKeyHelper runs an apiKeyHelper command and returns its trimmed output.
+// This variable allows tests to override the default behavior.
+var RunAPIKeyHelper = func(command string) (string, error) {
+	return secret.Resolve("cmd:" + command)
+}
+
+// GetAPIKeyHelper returns the apiKeyHelper command of settings, or "" if unset.
+func GetAPIKeyHelper(settings map[string]interface{}) string {
+	helper, _ := settings["apiKeyHelper"].(string)
+	return strings.TrimSpace(helper)
+}
+
+// GetCredentials returns the credentials Claude Code sends for settings:
+// ANTHROPIC_AUTH_TOKEN as Authorization: Bearer and ANTHROPIC_API_KEY as
+// x-api-key. If neither is set, the output of the apiKeyHelper command is
+// returned as both, as Claude Code sends it in both headers.
+func GetCredentials(settings map[string]interface{}) (authToken, apiKey string, err error) {
+	authToken = GetEnvString(settings, "ANTHROPIC_AUTH_TOKEN", "")
+	apiKey = GetEnvString(settings, "ANTHROPIC_API_KEY", "")
+	if authToken != "" || apiKey != "" {
+		return authToken, apiKey, nil
+	}
+	helper := GetAPIKeyHelper(settings)
+	if helper == "" {
+		return "", "", nil
+	}
+	key, err := RunAPIKeyHelper(helper)
+	if err != nil {
+		return "", "", fmt.Errorf("apiKeyHelper failed: %w", err)
+	}
+	if key == "" {
+		return "", "", fmt.Errorf("apiKeyHelper returned an empty key")
+	}
+	return key, key, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetCredentials(t *testing.T) {
+	original := RunAPIKeyHelper
+	defer func() { RunAPIKeyHelper = original }()
+	RunAPIKeyHelper = func(command string) (string, error) {
+		switch command {
+		case "fail":
+			return "", fmt.Errorf("exit status 1")
+		case "empty":
+			return "", nil
+		}
+		return "sk-helper", nil
+	}
+
+	env := func(kv ...string) map[string]interface{} {
+		m := make(map[string]interface{})
+		for i := 0; i < len(kv); i += 2 {
+			m[kv[i]] = kv[i+1]
+		}
+		return m
+	}
+	tests := []struct {
+		name       string
+		settings   map[string]interface{}
+		wantToken  string
+		wantAPIKey string
+		wantErr    bool
+	}{
+		{"none", map[string]interface{}{}, "", "", false},
+		{"auth token", map[string]interface{}{"env": env("ANTHROPIC_AUTH_TOKEN", "sk-t")}, "sk-t", "", false},
+		{"api key", map[string]interface{}{"env": env("ANTHROPIC_API_KEY", "sk-k")}, "", "sk-k", false},
+		{"both", map[string]interface{}{"env": env("ANTHROPIC_AUTH_TOKEN", "sk-t", "ANTHROPIC_API_KEY", "sk-k")}, "sk-t", "sk-k", false},
+		{"env wins over helper", map[string]interface{}{"apiKeyHelper": "fail", "env": env("ANTHROPIC_API_KEY", "sk-k")}, "", "sk-k", false},
+		{"helper", map[string]interface{}{"apiKeyHelper": "print-key"}, "sk-helper", "sk-helper", false},
+		{"failing helper", map[string]interface{}{"apiKeyHelper": "fail"}, "", "", true},
+		{"empty helper output", map[string]interface{}{"apiKeyHelper": "empty"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, apiKey, err := GetCredentials(tt.settings)
+			if token != tt.wantToken || apiKey != tt.wantAPIKey || (err != nil) != tt.wantErr {
+				t.Errorf("GetCredentials() = %q, %q, %v, want %q, %q, error %v", token, apiKey, err, tt.wantToken, tt.wantAPIKey, tt.wantErr)
+			}
+		})
+	}
+
+	if got := GetAuthToken(map[string]interface{}{"env": env("ANTHROPIC_API_KEY", "sk-k")}); got != "sk-k" {
+		t.Errorf("GetAuthToken() with API key = %q, want sk-k", got)
+	}
+	if got := GetAuthToken(map[string]interface{}{"apiKeyHelper": "fail"}); got != "PLEASE_SET_ANTHROPIC_AUTH_TOKEN" {
+		t.Errorf("GetAuthToken() with failing helper = %q, want placeholder", got)
+	}
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxBackups is the number of backups kept per file; older ones are removed.
+const MaxBackups = 20
+
+// backupTimeFormat is the timestamp prefix of backup IDs. It sorts lexically.
+const backupTimeFormat = "20060102-150405.000000"
+
+// Backup describes a saved copy of ccc.json or settings.json.
+type Backup struct {
+	ID   string    // File name in the backup directory, e.g. 20260101-120000.000000-settings.json
+	File string    // Backed up file name: ccc.json or settings.json
+	Path string    // Full path of the backup
+	Time time.Time // When the backup was taken
+}
+
+// GetBackupDir returns the directory holding backups of ccc.json and settings.json.
+func GetBackupDir() string {
+	return filepath.Join(GetDir(), "ccc", "backups")
+}
+
+// backupTargets maps backed up file names to their current paths.
+func backupTargets() map[string]string {
+	return map[string]string{
+		filepath.Base(GetConfigPath()):   GetConfigPath(),
+		filepath.Base(GetSettingsPath()): GetSettingsPath(),
+	}
+}
+
+// writeFileAtomic replaces path with data so that readers see either the old
+// or the new content, never a truncated file: the data is written to a
+// temporary file in the same directory, fsynced and renamed over path.
+// If path is a symlink, e.g. into a dotfiles repository, its target is
+// replaced and the symlink is kept.
+// The previous content, if any and different, is backed up first, unless
+// only current_provider changed (see needsBackup). Callers must hold Lock.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	name := filepath.Base(path)
+	path, err := resolveSymlinks(path)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if old, err := os.ReadFile(path); err == nil {
+		if bytes.Equal(old, data) {
+			return nil
+		}
+		if info, err := os.Stat(path); err == nil {
+			perm = info.Mode().Perm()
+		}
+		if needsBackup(name, old, data) {
+			if err := backupFile(name, old); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", path, err)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// resolveSymlinks follows path while it is a symlink and returns the file
+// it points to, which need not exist yet.
+func resolveSymlinks(path string) (string, error) {
+	for i := 0; i < 40; i++ {
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", fmt.Errorf("%s: too many levels of symbolic links", path)
+}
+
+// needsBackup reports whether old must be backed up before the named file is
+// replaced by data. Every provider switch writes current_provider, so a
+// change of current_provider alone is not backed up; otherwise switching
+// would soon rotate out the backups of actual edits.
+func needsBackup(name string, old, data []byte) bool {
+	if name != filepath.Base(GetConfigPath()) {
+		return true
+	}
+	var before, after map[string]json.RawMessage
+	if json.Unmarshal(old, &before) != nil || json.Unmarshal(data, &after) != nil {
+		return true
+	}
+	delete(before, "current_provider")
+	delete(after, "current_provider")
+	if len(before) != len(after) {
+		return true
+	}
+	for key, value := range before {
+		if !bytes.Equal(value, after[key]) {
+			return true
+		}
+	}
+	return false
+}
+
+// backupFile saves data as a new backup of the named file and prunes old
+// backups. An older backup with the same content is removed, so that
+// switching back and forth between providers keeps a single backup of each
+// version instead of rotating out the others.
+func backupFile(name string, data []byte) error {
+	backupDir := GetBackupDir()
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	var duplicate string
+	for _, b := range backups {
+		if b.File != name {
+			continue
+		}
+		if content, err := os.ReadFile(b.Path); err == nil && bytes.Equal(content, data) {
+			duplicate = b.Path
+			break
+		}
+	}
+
+	// IDs must be unique; on a clash within the same microsecond, move forward
+	t := time.Now()
+	for {
+		id := t.Format(backupTimeFormat) + "-" + name
+		f, err := os.OpenFile(filepath.Join(backupDir, id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if os.IsExist(err) {
+			t = t.Add(time.Microsecond)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		if duplicate != "" {
+			if err := os.Remove(duplicate); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		return pruneBackups(name)
+	}
+}
+
+// pruneBackups removes all but the newest MaxBackups backups of the named file.
+func pruneBackups(name string) error {
+	backups, err := ListBackups()
+	if err != nil {
+		return err
+	}
+	kept := 0
+	for _, b := range backups {
+		if b.File != name {
+			continue
+		}
+		kept++
+		if kept > MaxBackups {
+			if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseBackupID splits a backup ID into its timestamp and file name.
+func parseBackupID(id string) (time.Time, string, bool) {
+	if len(id) <= len(backupTimeFormat)+1 || id[len(backupTimeFormat)] != '-' {
+		return time.Time{}, "", false
+	}
+	t, err := time.ParseInLocation(backupTimeFormat, id[:len(backupTimeFormat)], time.Local)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	name := id[len(backupTimeFormat)+1:]
+	if _, ok := backupTargets()[name]; !ok {
+		return time.Time{}, "", false
+	}
+	return t, name, true
+}
+
+// ListBackups returns all backups, newest first.
+func ListBackups() ([]Backup, error) {
+	entries, err := os.ReadDir(GetBackupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		t, name, ok := parseBackupID(entry.Name())
+		if entry.IsDir() || !ok {
+			continue
+		}
+		backups = append(backups, Backup{
+			ID:   entry.Name(),
+			File: name,
+			Path: filepath.Join(GetBackupDir(), entry.Name()),
+			Time: t,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ID > backups[j].ID })
+	return backups, nil
+}
+
+// FindBackup returns the backup selected by query: a backup ID, a unique ID
+// prefix, or a file name (ccc.json or settings.json) for its newest backup.
+// An empty query selects the newest backup of any file.
+func FindBackup(query string) (*Backup, error) {
+	backups, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no backups found in %s", GetBackupDir())
+	}
+	if query == "" {
+		return &backups[0], nil
+	}
+
+	if _, ok := backupTargets()[query]; ok {
+		for i := range backups {
+			if backups[i].File == query {
+				return &backups[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no backups of %s found", query)
+	}
+
+	var matches []*Backup
+	for i := range backups {
+		if backups[i].ID == query {
+			return &backups[i], nil
+		}
+		if strings.HasPrefix(backups[i].ID, query) {
+			matches = append(matches, &backups[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("backup '%s' not found", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("backup '%s' is ambiguous (%d matches)", query, len(matches))
+	}
+}
+
+// RestoreBackup writes the backup over the file it was taken from.
+// The current content is backed up first, so a restore can be undone.
+func RestoreBackup(b *Backup) error {
+	target, ok := backupTargets()[b.File]
+	if !ok {
+		return fmt.Errorf("unknown backup file '%s'", b.File)
+	}
+
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("backup %s is not valid JSON", b.ID)
+	}
+
+	unlock, err := Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := writeFileAtomic(target, data, 0644); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", target, err)
+	}
+	return nil
+}
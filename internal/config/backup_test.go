@@ -0,0 +1,299 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	path := GetSettingsPath()
+	if err := SaveSettings(map[string]interface{}{"model": "opus"}); err != nil {
+		t.Fatalf("SaveSettings() error = %v", err)
+	}
+
+	// First write has nothing to back up
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 0 {
+		t.Fatalf("got %d backups after first write, want 0", len(backups))
+	}
+
+	if err := SaveSettings(map[string]interface{}{"model": "sonnet"}); err != nil {
+		t.Fatalf("SaveSettings() error = %v", err)
+	}
+	// Writing identical content is a no-op
+	if err := SaveSettings(map[string]interface{}{"model": "sonnet"}); err != nil {
+		t.Fatalf("SaveSettings() error = %v", err)
+	}
+
+	backups, _ = ListBackups()
+	if len(backups) != 1 {
+		t.Fatalf("got %d backups, want 1", len(backups))
+	}
+	if backups[0].File != "settings.json" {
+		t.Errorf("backup file = %s, want settings.json", backups[0].File)
+	}
+	data, _ := os.ReadFile(backups[0].Path)
+	if !strings.Contains(string(data), "opus") {
+		t.Errorf("backup should hold the previous content, got %s", data)
+	}
+
+	// No temporary files are left behind
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp-") {
+			t.Errorf("temporary file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	path := GetSettingsPath()
+	if err := os.WriteFile(path, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSettings(map[string]interface{}{"model": "opus"}); err != nil {
+		t.Fatalf("SaveSettings() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	for i := 0; i < MaxBackups+5; i++ {
+		if err := SaveSettings(map[string]interface{}{"n": i}); err != nil {
+			t.Fatalf("SaveSettings() error = %v", err)
+		}
+	}
+	cfg := &Config{Providers: map[string]map[string]interface{}{"kimi": {}}}
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	cfg.Providers["glm"] = map[string]interface{}{}
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	counts := map[string]int{}
+	for _, b := range backups {
+		counts[b.File]++
+	}
+	if counts["settings.json"] != MaxBackups {
+		t.Errorf("settings.json backups = %d, want %d", counts["settings.json"], MaxBackups)
+	}
+	if counts["ccc.json"] != 1 {
+		t.Errorf("ccc.json backups = %d, want 1", counts["ccc.json"])
+	}
+
+	// The newest backup holds the content before the last write
+	latest, err := FindBackup("settings.json")
+	if err != nil {
+		t.Fatalf("FindBackup() error = %v", err)
+	}
+	data, _ := os.ReadFile(latest.Path)
+	if !strings.Contains(string(data), `"n": 23`) {
+		t.Errorf("latest settings.json backup = %s, want n=23", data)
+	}
+}
+
+func TestBackupChurn(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	cfg := &Config{Providers: map[string]map[string]interface{}{"kimi": {}, "glm": {}}}
+	if err := Save(cfg); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// Switching providers back and forth
+	for i := 0; i < MaxBackups; i++ {
+		cfg.CurrentProvider = []string{"kimi", "glm"}[i%2]
+		if err := Save(cfg); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if err := SaveSettings(map[string]interface{}{"model": cfg.CurrentProvider}); err != nil {
+			t.Fatalf("SaveSettings() error = %v", err)
+		}
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	counts := map[string]int{}
+	for _, b := range backups {
+		counts[b.File]++
+	}
+	if counts["ccc.json"] != 0 {
+		t.Errorf("ccc.json backups = %d, want none for current_provider changes", counts["ccc.json"])
+	}
+	if counts["settings.json"] != 2 {
+		t.Errorf("settings.json backups = %d, want one per distinct version", counts["settings.json"])
+	}
+
+	// The newest backup is still the content before the last write
+	latest, err := FindBackup("settings.json")
+	if err != nil {
+		t.Fatalf("FindBackup() error = %v", err)
+	}
+	if data, _ := os.ReadFile(latest.Path); !strings.Contains(string(data), `"kimi"`) {
+		t.Errorf("latest settings.json backup = %s, want model kimi", data)
+	}
+}
+
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	dotfiles := t.TempDir()
+	target := filepath.Join(dotfiles, "claude-settings.json")
+	if err := os.WriteFile(target, []byte(`{"model": "opus"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, GetSettingsPath()); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := SaveSettings(map[string]interface{}{"model": "sonnet"}); err != nil {
+		t.Fatalf("SaveSettings() error = %v", err)
+	}
+	if info, err := os.Lstat(GetSettingsPath()); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("settings.json is no longer a symlink: %v", err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "sonnet") {
+		t.Errorf("symlink target = %s, want the new content", data)
+	}
+	if backup, err := FindBackup("settings.json"); err != nil {
+		t.Errorf("FindBackup() error = %v, want a backup named after settings.json", err)
+	} else if data, _ := os.ReadFile(backup.Path); !strings.Contains(string(data), "opus") {
+		t.Errorf("backup = %s, want the previous content", data)
+	}
+
+	// A dangling symlink is written through as well
+	if err := os.Remove(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSettings(map[string]interface{}{"model": "haiku"}); err != nil {
+		t.Fatalf("SaveSettings() error = %v", err)
+	}
+	if data, _ := os.ReadFile(target); !strings.Contains(string(data), "haiku") {
+		t.Errorf("symlink target = %s, want the new content", data)
+	}
+}
+
+func TestFindBackup(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if _, err := FindBackup(""); err == nil {
+		t.Error("FindBackup() should fail without backups")
+	}
+
+	backupDir := GetBackupDir()
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{
+		"20260101-120000.000000-settings.json",
+		"20260101-130000.000000-ccc.json",
+		"20260102-120000.000000-settings.json",
+	}
+	for _, id := range ids {
+		if err := os.WriteFile(filepath.Join(backupDir, id), []byte(`{}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Unrelated files are ignored
+	os.WriteFile(filepath.Join(backupDir, "notes.txt"), []byte("x"), 0600)
+
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"", ids[2], false},
+		{"settings.json", ids[2], false},
+		{"ccc.json", ids[1], false},
+		{ids[0], ids[0], false},
+		{"20260101-13", ids[1], false},
+		{"20260101", "", true},
+		{"2027", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := FindBackup(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FindBackup(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if err == nil && got.ID != tt.want {
+				t.Errorf("FindBackup(%q) = %s, want %s", tt.query, got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	_, cleanup := setupTestDir(t)
+	defer cleanup()
+
+	if err := SaveSettings(map[string]interface{}{"model": "opus"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSettings(map[string]interface{}{"model": "sonnet"}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := FindBackup("settings.json")
+	if err != nil {
+		t.Fatalf("FindBackup() error = %v", err)
+	}
+	if err := RestoreBackup(backup); err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+
+	settings, err := LoadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings["model"] != "opus" {
+		t.Errorf("model = %v, want opus", settings["model"])
+	}
+
+	// The restore itself is undoable
+	backups, _ := ListBackups()
+	if len(backups) != 2 {
+		t.Errorf("got %d backups after restore, want 2", len(backups))
+	}
+
+	t.Run("invalid backup", func(t *testing.T) {
+		id := "20260101-120000.000000-settings.json"
+		path := filepath.Join(GetBackupDir(), id)
+		if err := os.WriteFile(path, []byte(`{"truncated`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := RestoreBackup(&Backup{ID: id, File: "settings.json", Path: path}); err == nil {
+			t.Error("RestoreBackup() should reject invalid JSON")
+		}
+	})
+}
@@ -4,10 +4,15 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/guyskk/ccc/internal/secret"
 )
 
 // GetDirFunc is a function that returns the Claude configuration directory.
@@ -29,6 +34,10 @@ func GetDir() string {
 	return GetDirFunc()
 }
 
+// ProjectConfigName is the file name of a project-level configuration,
+// discovered by walking up from the working directory.
+const ProjectConfigName = ".ccc.json"
+
 // Config represents the ccc.json configuration structure.
 // Settings and Providers use dynamic maps to handle arbitrary Claude settings fields.
 type Config struct {
@@ -36,8 +45,61 @@ type Config struct {
 	ClaudeArgs      []string                          `json:"claude_args,omitempty"`
 	CurrentProvider string                            `json:"current_provider"`
 	Providers       map[string]map[string]interface{} `json:"providers"`
+	// DefaultProvider is used when current_provider is not set or no longer
+	// exists, instead of the first declared provider.
+	DefaultProvider string `json:"default_provider,omitempty"`
+	// Aliases maps short names to provider names, next to the
+	// `aliases` lists of the providers themselves.
+	Aliases map[string]string `json:"aliases,omitempty"`
+	// Isolated launches sessions without writing settings.json or ccc.json,
+	// so concurrent sessions with different providers cannot interfere.
+	Isolated bool `json:"isolated,omitempty"`
+	// StrictProvider makes an unknown provider name an error instead of
+	// falling back to the current provider.
+	StrictProvider bool `json:"strict_provider,omitempty"`
+	// TrustedProjects maps the path of each trusted project config to the
+	// digest of its content, see TrustProject.
+	TrustedProjects map[string]string `json:"trusted_projects,omitempty"`
+
+	// Sources lists the configuration files that contributed to this config,
+	// in merge order (user config first, then the project config if any).
+	Sources []string `json:"-"`
+	// UntrustedProject is the path of a project config that was found but
+	// ignored, because it was never trusted or has changed since.
+	UntrustedProject string `json:"-"`
+
+	// project records what a project config changed, so that Save only
+	// ever writes user-level configuration back to ccc.json.
+	project *projectOverlay
+
+	// order lists the providers in the order they are declared, see ProviderNames.
+	order []string
+}
+
+// ProjectConfig represents a project-level .ccc.json.
+// It can pin a provider, add or replace providers, and override claude_args.
+type ProjectConfig struct {
+	Provider   string                            `json:"provider,omitempty"`
+	ClaudeArgs []string                          `json:"claude_args,omitempty"`
+	Providers  map[string]map[string]interface{} `json:"providers,omitempty"`
+
+	order []string // Declaration order of the providers
 }
 
+// projectOverlay holds the user-level values shadowed by a project config.
+type projectOverlay struct {
+	pinned          string
+	providers       map[string]bool
+	userProviders   map[string]map[string]interface{}
+	userClaudeArgs  []string
+	userCurrent     string
+	claudeArgsInUse bool
+}
+
+// GetWorkDirFunc returns the directory where project config discovery starts.
+// This variable allows tests to override the default behavior.
+var GetWorkDirFunc = os.Getwd
+
 // GetConfigPath returns the path to ccc.json.
 func GetConfigPath() string {
 	return filepath.Join(GetDir(), "ccc.json")
@@ -48,8 +110,45 @@ func GetSettingsPath() string {
 	return filepath.Join(GetDir(), "settings.json")
 }
 
-// Load reads and parses the ccc.json configuration file.
+// Load reads the ccc.json configuration file and merges the nearest
+// project-level .ccc.json (if any) over it. A project config is only merged
+// once its current content has been trusted, see TrustProject; otherwise
+// its path is recorded in UntrustedProject.
 func Load() (*Config, error) {
+	cfg, err := LoadUser()
+	if err != nil {
+		return nil, err
+	}
+
+	projectPath := FindProjectConfig()
+	if projectPath == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config file: %w", err)
+	}
+	if !cfg.isTrusted(projectPath, data) {
+		// A cloned repository must not be able to add hooks, claude_args or
+		// endpoints receiving the user's credentials until it is reviewed
+		cfg.UntrustedProject = projectPath
+		return cfg, nil
+	}
+	project, err := parseProject(projectPath, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProject(cfg, project); err != nil {
+		return nil, fmt.Errorf("invalid project config %s: %w", projectPath, err)
+	}
+	cfg.Sources = append(cfg.Sources, projectPath)
+
+	return cfg, nil
+}
+
+// LoadUser reads and parses the ccc.json configuration file only,
+// ignoring any project-level configuration.
+func LoadUser() (*Config, error) {
 	configPath := GetConfigPath()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -60,12 +159,188 @@ func Load() (*Config, error) {
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	cfg.Sources = []string{configPath}
 
 	return &cfg, nil
 }
 
+// FindProjectConfig walks up from the working directory and returns the path
+// of the nearest project .ccc.json, or empty string if there is none.
+func FindProjectConfig() string {
+	dir, err := GetWorkDirFunc()
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectConfigName)
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads and parses a project-level .ccc.json.
+func LoadProject(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config file: %w", err)
+	}
+	return parseProject(path, data)
+}
+
+// parseProject parses the content of the project config at path.
+func parseProject(path string, data []byte) (*ProjectConfig, error) {
+	var project ProjectConfig
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("failed to parse project config file %s: %w", path, err)
+	}
+
+	return &project, nil
+}
+
+// applyProject merges a project config over the user config.
+// Project providers replace user providers of the same name.
+func applyProject(cfg *Config, project *ProjectConfig) error {
+	overlay := &projectOverlay{
+		providers:      make(map[string]bool),
+		userProviders:  make(map[string]map[string]interface{}),
+		userClaudeArgs: cfg.ClaudeArgs,
+		userCurrent:    cfg.CurrentProvider,
+	}
+
+	// Project providers come after the user providers they do not replace
+	cfg.order = append(cfg.ProviderNames(), project.order...)
+	if len(project.Providers) > 0 && cfg.Providers == nil {
+		cfg.Providers = make(map[string]map[string]interface{})
+	}
+	for name, p := range project.Providers {
+		// A cloned repository must not be able to run commands on launch.
+		// Values are checked as ResolveSecrets sees them, after ${VAR} expansion.
+		for key, value := range GetEnv(p) {
+			if str, ok := value.(string); ok {
+				if scheme, ok := secret.Scheme(os.ExpandEnv(str)); ok && scheme == "cmd" {
+					return fmt.Errorf("provider '%s': %s uses a cmd: secret reference, which is only allowed in %s", name, key, GetConfigPath())
+				}
+			}
+		}
+		if _, ok := p["apiKeyHelper"]; ok {
+			return fmt.Errorf("provider '%s': apiKeyHelper runs a command, which is only allowed in %s", name, GetConfigPath())
+		}
+		if existing, exists := cfg.Providers[name]; exists {
+			overlay.userProviders[name] = existing
+		}
+		overlay.providers[name] = true
+		cfg.Providers[name] = p
+	}
+
+	if project.ClaudeArgs != nil {
+		cfg.ClaudeArgs = project.ClaudeArgs
+		overlay.claudeArgsInUse = true
+	}
+
+	if project.Provider != "" {
+		if _, exists := cfg.Providers[project.Provider]; !exists {
+			return fmt.Errorf("pinned provider '%s' not found", project.Provider)
+		}
+		cfg.CurrentProvider = project.Provider
+		overlay.pinned = project.Provider
+	}
+
+	cfg.project = overlay
+	return nil
+}
+
+// userView returns the configuration to persist in ccc.json, with everything
+// contributed by a project config replaced by the shadowed user values.
+func userView(cfg *Config) *Config {
+	overlay := cfg.project
+	if overlay == nil {
+		return cfg
+	}
+
+	view := *cfg
+	view.Providers = make(map[string]map[string]interface{}, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		if overlay.providers[name] {
+			if userProvider, shadowed := overlay.userProviders[name]; shadowed {
+				view.Providers[name] = userProvider
+			}
+			continue
+		}
+		view.Providers[name] = p
+	}
+	if overlay.claudeArgsInUse {
+		view.ClaudeArgs = overlay.userClaudeArgs
+	}
+	if cfg.CurrentProvider == overlay.pinned || overlay.providers[cfg.CurrentProvider] {
+		// Project-level choices never change the global current provider
+		view.CurrentProvider = overlay.userCurrent
+	}
+	return &view
+}
+
 // Save writes the configuration to ccc.json.
+// Values contributed by a project config are never written.
+// The file is replaced atomically and the previous version is backed up.
+// To change a configuration loaded earlier, use UpdateConfig instead, which
+// keeps the changes other ccc processes made in the meantime.
 func Save(cfg *Config) error {
+	unlock, err := Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return writeConfig(cfg)
+}
+
+// UpdateConfig performs a locked read-modify-write of ccc.json.
+// update receives the user configuration as it is on disk, without any
+// project config (empty if the file does not exist), and modifies it.
+// Nothing is written if update returns an error.
+func UpdateConfig(update func(cfg *Config) error) error {
+	unlock, err := Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := LoadUser()
+	if errors.Is(err, fs.ErrNotExist) {
+		cfg = &Config{Settings: map[string]interface{}{}}
+	} else if err != nil {
+		return err
+	}
+	if cfg.Providers == nil {
+		cfg.Providers = make(map[string]map[string]interface{})
+	}
+	if err := update(cfg); err != nil {
+		return err
+	}
+	return writeConfig(cfg)
+}
+
+// SaveCurrentProvider makes name the current provider of cfg and records it
+// in ccc.json with UpdateConfig. Like Save, it leaves the global current
+// provider unchanged for a provider pinned or defined by a project config.
+func SaveCurrentProvider(cfg *Config, name string) error {
+	cfg.CurrentProvider = name
+	if overlay := cfg.project; overlay != nil && (name == overlay.pinned || overlay.providers[name]) {
+		return nil
+	}
+	return UpdateConfig(func(user *Config) error {
+		user.CurrentProvider = name
+		return nil
+	})
+}
+
+// writeConfig writes ccc.json. Callers must hold the lock.
+func writeConfig(cfg *Config) error {
 	configPath := GetConfigPath()
 
 	// Ensure config directory exists
@@ -74,12 +349,12 @@ func Save(cfg *Config) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	data, err := json.MarshalIndent(userView(cfg), "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	if err := writeFileAtomic(configPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -87,7 +362,43 @@ func Save(cfg *Config) error {
 }
 
 // SaveSettings writes the settings to settings.json.
+// The file is replaced atomically and the previous version is backed up.
 func SaveSettings(settings map[string]interface{}) error {
+	unlock, err := Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return writeSettings(settings)
+}
+
+// UpdateSettings performs a locked read-modify-write of settings.json.
+// update receives the current settings (empty if the file does not exist)
+// and returns the settings to write.
+func UpdateSettings(update func(settings map[string]interface{}) (map[string]interface{}, error)) error {
+	unlock, err := Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	settings, err := LoadSettings()
+	if err != nil {
+		return err
+	}
+	if settings == nil {
+		settings = make(map[string]interface{})
+	}
+	updated, err := update(settings)
+	if err != nil {
+		return err
+	}
+	return writeSettings(updated)
+}
+
+// writeSettings writes settings.json. Callers must hold the lock.
+func writeSettings(settings map[string]interface{}) error {
 	settingsPath := GetSettingsPath()
 
 	// Ensure settings directory exists
@@ -101,7 +412,7 @@ func SaveSettings(settings map[string]interface{}) error {
 		return fmt.Errorf("failed to marshal settings: %w", err)
 	}
 
-	if err := os.WriteFile(settingsPath, data, 0644); err != nil {
+	if err := writeFileAtomic(settingsPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write settings file: %w", err)
 	}
 
@@ -128,6 +439,11 @@ func deepCopy(original map[string]interface{}) map[string]interface{} {
 	return copied
 }
 
+// DeepCopy returns a deep copy of a settings or provider map.
+func DeepCopy(original map[string]interface{}) map[string]interface{} {
+	return deepCopy(original)
+}
+
 // deepCopySlice creates a deep copy of a []interface{}.
 func deepCopySlice(original []interface{}) []interface{} {
 	if original == nil {
@@ -174,6 +490,132 @@ func DeepMerge(base, provider map[string]interface{}) map[string]interface{} {
 	return result
 }
 
+// providerMetaKeys lists provider fields that are consumed by ccc itself
+// and must never be merged into Claude settings.
+var providerMetaKeys = []string{
+	"extends", "failover", "validate",
+	"proxy", "ca_file", "client_cert", "client_key", "insecure_skip_verify",
+	"headers", "auth_style", "aliases",
+}
+
+// ResolveProvider returns the provider configuration with its `extends`
+// chain applied. Each provider is deep-merged over its parent, so a child
+// only needs to declare the fields it changes. Chains may be arbitrarily
+// deep; unknown parents and cycles are reported as errors.
+// The returned map is a new copy without the `extends` field.
+func ResolveProvider(cfg *Config, name string) (map[string]interface{}, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+	return resolveProvider(cfg, name, nil)
+}
+
+func resolveProvider(cfg *Config, name string, chain []string) (map[string]interface{}, error) {
+	for _, seen := range chain {
+		if seen == name {
+			return nil, fmt.Errorf("provider inheritance cycle: %s", strings.Join(append(chain, name), " -> "))
+		}
+	}
+	chain = append(chain, name)
+
+	providerSettings, exists := cfg.Providers[name]
+	if !exists {
+		if len(chain) > 1 {
+			return nil, fmt.Errorf("provider '%s' extends unknown provider '%s'", chain[len(chain)-2], name)
+		}
+		return nil, fmt.Errorf("provider '%s' not found in configuration", name)
+	}
+
+	extendsVal, hasExtends := providerSettings["extends"]
+	if !hasExtends {
+		return deepCopy(providerSettings), nil
+	}
+	parentName, ok := extendsVal.(string)
+	if !ok || parentName == "" {
+		return nil, fmt.Errorf("provider '%s': extends must be a provider name", name)
+	}
+
+	parent, err := resolveProvider(cfg, parentName, chain)
+	if err != nil {
+		return nil, err
+	}
+	resolved := DeepMerge(parent, deepCopy(providerSettings))
+	delete(resolved, "extends")
+	return resolved, nil
+}
+
+// ProviderSettings returns a copy of the provider configuration with the
+// ccc-only fields removed, ready to be merged into Claude settings.
+func ProviderSettings(provider map[string]interface{}) map[string]interface{} {
+	result := deepCopy(provider)
+	for _, key := range providerMetaKeys {
+		delete(result, key)
+	}
+	return result
+}
+
+// GetFailover returns the member provider names of a failover group,
+// declared as `"failover": ["kimi", "glm"]`.
+// Returns nil if the provider is not a failover group.
+func GetFailover(provider map[string]interface{}) []string {
+	members, ok := provider["failover"].([]interface{})
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(members))
+	for _, m := range members {
+		if name, ok := m.(string); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// CheckProvider checks a provider configuration without touching the network:
+// its extends chain must resolve, a failover group must list existing
+// non-group providers, its network, headers and auth_style settings must be
+// well-formed, and ANTHROPIC_BASE_URL, if set, must be an http(s) URL.
+func CheckProvider(cfg *Config, name string) error {
+	resolved, err := ResolveProvider(cfg, name)
+	if err != nil {
+		return err
+	}
+
+	if _, isGroup := resolved["failover"]; isGroup {
+		members := GetFailover(resolved)
+		if len(members) == 0 {
+			return fmt.Errorf("provider '%s': failover must be a non-empty list of provider names", name)
+		}
+		for _, member := range members {
+			member = ResolveAlias(cfg, member)
+			if _, exists := cfg.Providers[member]; !exists {
+				return fmt.Errorf("provider '%s': failover member '%s' not found", name, member)
+			}
+			if m, err := ResolveProvider(cfg, member); err == nil && m["failover"] != nil {
+				return fmt.Errorf("provider '%s': failover member '%s' is itself a failover group", name, member)
+			}
+		}
+		return nil
+	}
+
+	if _, err := GetNetwork(resolved); err != nil {
+		return fmt.Errorf("provider '%s': %w", name, err)
+	}
+	if _, err := GetHeaders(resolved); err != nil {
+		return fmt.Errorf("provider '%s': %w", name, err)
+	}
+	if _, err := GetAuthStyle(resolved); err != nil {
+		return fmt.Errorf("provider '%s': %w", name, err)
+	}
+	if baseURL, ok := GetEnv(resolved)["ANTHROPIC_BASE_URL"].(string); ok && baseURL != "" && !strings.Contains(baseURL, "${") {
+		u, err := url.Parse(baseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("provider '%s': invalid ANTHROPIC_BASE_URL '%s' (must be an http:// or https:// URL)", name, baseURL)
+		}
+	}
+	return nil
+}
+
 // GetEnv extracts the env map from settings.
 // Returns nil if env doesn't exist or is not a map.
 func GetEnv(settings map[string]interface{}) map[string]interface{} {
@@ -203,14 +645,19 @@ func GetEnvString(settings map[string]interface{}, key, defaultValue string) str
 	return defaultValue
 }
 
-// GetAuthToken extracts the ANTHROPIC_AUTH_TOKEN from settings.
-// Returns a placeholder if the token is not set.
+// GetAuthToken extracts the credential from settings: ANTHROPIC_AUTH_TOKEN,
+// ANTHROPIC_API_KEY, or the output of apiKeyHelper, as in GetCredentials.
+// Returns a placeholder if none is set or the helper fails.
 func GetAuthToken(settings map[string]interface{}) string {
-	token := GetEnvString(settings, "ANTHROPIC_AUTH_TOKEN", "")
-	if token == "" {
-		return "PLEASE_SET_ANTHROPIC_AUTH_TOKEN"
+	authToken, apiKey, err := GetCredentials(settings)
+	switch {
+	case err != nil:
+	case authToken != "":
+		return authToken
+	case apiKey != "":
+		return apiKey
 	}
-	return token
+	return "PLEASE_SET_ANTHROPIC_AUTH_TOKEN"
 }
 
 // GetBaseURL extracts the ANTHROPIC_BASE_URL from settings.
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Auth styles: how a provider expects ANTHROPIC_AUTH_TOKEN to be sent.
+const (
+	AuthStyleBearer = "bearer"    // Authorization: Bearer <token>, the default
+	AuthStyleAPIKey = "x-api-key" // x-api-key: <token>
+)
+
+// GetAuthStyle returns the auth_style of a resolved provider, AuthStyleBearer if unset.
+func GetAuthStyle(provider map[string]interface{}) (string, error) {
+	raw, ok := provider["auth_style"]
+	if !ok {
+		return AuthStyleBearer, nil
+	}
+	switch style, _ := raw.(string); style {
+	case AuthStyleBearer, AuthStyleAPIKey:
+		return style, nil
+	default:
+		return "", fmt.Errorf("unknown auth_style '%v' (supported: %s, %s)", raw, AuthStyleBearer, AuthStyleAPIKey)
+	}
+}
+
+// GetHeaders returns the custom headers of a resolved provider: the lines of
+// its env ANTHROPIC_CUSTOM_HEADERS, overridden by its `headers` field, e.g.
+// {"headers": {"X-Tenant": "team-a"}}. ${VAR} references in the headers
+// field are expanded. Returns nil if the provider has no custom headers.
+func GetHeaders(provider map[string]interface{}) (map[string]string, error) {
+	headers := make(map[string]string)
+	if custom, ok := GetEnv(provider)["ANTHROPIC_CUSTOM_HEADERS"].(string); ok {
+		headers = ParseCustomHeaders(custom)
+	}
+
+	if raw, ok := provider["headers"]; ok {
+		fields, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("headers must be an object of header names to values")
+		}
+		for name, v := range fields {
+			value, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("header '%s' must be a string", name)
+			}
+			if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+				return nil, fmt.Errorf("invalid header name '%s'", name)
+			}
+			value = os.ExpandEnv(value)
+			if strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("header '%s' must not contain line breaks", name)
+			}
+			// Header names are case-insensitive
+			for existing := range headers {
+				if strings.EqualFold(existing, name) {
+					delete(headers, existing)
+				}
+			}
+			headers[name] = value
+		}
+	}
+
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// ParseCustomHeaders parses the "Name: value" lines of ANTHROPIC_CUSTOM_HEADERS.
+// Lines without a colon are ignored.
+func ParseCustomHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// FormatCustomHeaders formats headers for ANTHROPIC_CUSTOM_HEADERS:
+// one "Name: value" line per header, sorted by name.
+func FormatCustomHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, name+": "+headers[name])
+	}
+	return strings.Join(lines, "\n")
+}
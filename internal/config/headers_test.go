@@ -0,0 +1,92 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetHeaders(t *testing.T) {
+	t.Setenv("CCC_TEST_TENANT", "team-a")
+
+	tests := []struct {
+		name     string
+		provider map[string]interface{}
+		want     map[string]string
+		wantErr  string
+	}{
+		{"none", map[string]interface{}{}, nil, ""},
+		{
+			"headers field",
+			map[string]interface{}{"headers": map[string]interface{}{"X-Tenant": "${CCC_TEST_TENANT}"}},
+			map[string]string{"X-Tenant": "team-a"},
+			"",
+		},
+		{
+			"field overrides env case-insensitively",
+			map[string]interface{}{
+				"env":     map[string]interface{}{"ANTHROPIC_CUSTOM_HEADERS": "X-Tenant: old\nX-Region: eu"},
+				"headers": map[string]interface{}{"x-tenant": "new"},
+			},
+			map[string]string{"x-tenant": "new", "X-Region": "eu"},
+			"",
+		},
+		{"not an object", map[string]interface{}{"headers": "X-Tenant: a"}, nil, "must be an object"},
+		{"non-string value", map[string]interface{}{"headers": map[string]interface{}{"X-N": 1.0}}, nil, "must be a string"},
+		{"invalid name", map[string]interface{}{"headers": map[string]interface{}{"X Tenant": "a"}}, nil, "invalid header name"},
+		{"line break", map[string]interface{}{"headers": map[string]interface{}{"X-Tenant": "a\nb"}}, nil, "line breaks"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetHeaders(tt.provider)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetHeaders() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetHeaders() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetHeaders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAuthStyle(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		want    string
+		wantErr bool
+	}{
+		{nil, AuthStyleBearer, false},
+		{"bearer", AuthStyleBearer, false},
+		{"", "", true},
+		{"x-api-key", AuthStyleAPIKey, false},
+		{"basic", "", true},
+		{true, "", true},
+	}
+	for _, tt := range tests {
+		provider := map[string]interface{}{}
+		if tt.value != nil {
+			provider["auth_style"] = tt.value
+		}
+		got, err := GetAuthStyle(provider)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("GetAuthStyle(%v) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestCustomHeadersRoundTrip(t *testing.T) {
+	headers := map[string]string{"X-Tenant": "team-a", "Anthropic-Beta": "a,b"}
+	formatted := FormatCustomHeaders(headers)
+	if formatted != "Anthropic-Beta: a,b\nX-Tenant: team-a" {
+		t.Errorf("FormatCustomHeaders() = %q", formatted)
+	}
+	if got := ParseCustomHeaders(formatted + "\n\nbogus line"); !reflect.DeepEqual(got, headers) {
+		t.Errorf("ParseCustomHeaders() = %v, want %v", got, headers)
+	}
+}
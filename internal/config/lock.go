@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// GetLockPath returns the path of the lock file guarding ccc.json and settings.json.
+func GetLockPath() string {
+	return filepath.Join(GetDir(), "ccc", "ccc.lock")
+}
+
+// Lock acquires an exclusive inter-process lock guarding writes to ccc.json
+// and settings.json, so concurrent ccc launches never interleave their JSON.
+// It blocks until the lock is available. The returned function releases it.
+func Lock() (func(), error) {
+	lockPath := GetLockPath()
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Network holds the connection settings of a provider, declared next to
+// its env:
+//
+//	"proxy": "http://proxy.corp:3128",
+//	"ca_file": "~/certs/corp-ca.pem",
+//	"client_cert": "~/certs/me.crt",
+//	"client_key": "~/certs/me.key",
+//	"insecure_skip_verify": false
+type Network struct {
+	Proxy              string // HTTP(S) or SOCKS5 proxy URL
+	CAFile             string // PEM bundle trusted in addition to the system CAs
+	ClientCert         string // PEM client certificate for mutual TLS
+	ClientKey          string // PEM private key of ClientCert
+	InsecureSkipVerify bool   // Skip server certificate verification
+}
+
+// GetNetwork returns the network settings of a resolved provider, or nil if
+// it declares none. ${VAR} references are expanded and a leading ~ in the
+// file paths is replaced by the home directory.
+func GetNetwork(provider map[string]interface{}) (*Network, error) {
+	n := &Network{}
+	set := false
+	for key, dst := range map[string]*string{
+		"proxy":       &n.Proxy,
+		"ca_file":     &n.CAFile,
+		"client_cert": &n.ClientCert,
+		"client_key":  &n.ClientKey,
+	} {
+		raw, ok := provider[key]
+		if !ok {
+			continue
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", key)
+		}
+		*dst = os.ExpandEnv(s)
+		if key != "proxy" {
+			*dst = expandHome(*dst)
+		}
+		set = set || *dst != ""
+	}
+	if raw, ok := provider["insecure_skip_verify"]; ok {
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("insecure_skip_verify must be true or false")
+		}
+		n.InsecureSkipVerify = b
+		set = set || b
+	}
+	if !set {
+		return nil, nil
+	}
+
+	if n.Proxy != "" {
+		u, err := url.Parse(n.Proxy)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return nil, fmt.Errorf("invalid proxy '%s' (must be an http://, https:// or socks5:// URL)", n.Proxy)
+		}
+	}
+	if (n.ClientCert == "") != (n.ClientKey == "") {
+		return nil, fmt.Errorf("client_cert and client_key must be set together")
+	}
+	return n, nil
+}
+
+// IsSOCKS reports whether the proxy is a SOCKS5 proxy. ccc's own requests
+// support it, but Claude Code only supports HTTP(S) proxies.
+func (n *Network) IsSOCKS() bool {
+	return n != nil && strings.HasPrefix(strings.ToLower(n.Proxy), "socks5://")
+}
+
+// Env returns the environment variables that make Claude Code use the
+// network settings: HTTPS_PROXY and HTTP_PROXY, NODE_EXTRA_CA_CERTS,
+// CLAUDE_CODE_CLIENT_CERT and CLAUDE_CODE_CLIENT_KEY, and
+// NODE_TLS_REJECT_UNAUTHORIZED=0 to skip certificate verification.
+func (n *Network) Env() map[string]interface{} {
+	env := make(map[string]interface{})
+	if n == nil {
+		return env
+	}
+	if n.Proxy != "" {
+		env["HTTPS_PROXY"] = n.Proxy
+		env["HTTP_PROXY"] = n.Proxy
+	}
+	if n.CAFile != "" {
+		env["NODE_EXTRA_CA_CERTS"] = n.CAFile
+	}
+	if n.ClientCert != "" {
+		env["CLAUDE_CODE_CLIENT_CERT"] = n.ClientCert
+		env["CLAUDE_CODE_CLIENT_KEY"] = n.ClientKey
+	}
+	if n.InsecureSkipVerify {
+		env["NODE_TLS_REJECT_UNAUTHORIZED"] = "0"
+	}
+	return env
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetNetwork(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	t.Setenv("CCC_TEST_PROXY_HOST", "proxy.corp")
+
+	tests := []struct {
+		name     string
+		provider map[string]interface{}
+		want     *Network
+		wantErr  string
+	}{
+		{"none", map[string]interface{}{"env": map[string]interface{}{}}, nil, ""},
+		{"empty values", map[string]interface{}{"proxy": "", "insecure_skip_verify": false}, nil, ""},
+		{
+			"all settings",
+			map[string]interface{}{
+				"proxy":                "http://${CCC_TEST_PROXY_HOST}:3128",
+				"ca_file":              "~/certs/ca.pem",
+				"client_cert":          "/etc/ssl/me.crt",
+				"client_key":           "/etc/ssl/me.key",
+				"insecure_skip_verify": true,
+			},
+			&Network{
+				Proxy:              "http://proxy.corp:3128",
+				CAFile:             filepath.Join(home, "certs/ca.pem"),
+				ClientCert:         "/etc/ssl/me.crt",
+				ClientKey:          "/etc/ssl/me.key",
+				InsecureSkipVerify: true,
+			},
+			"",
+		},
+		{"socks5 proxy", map[string]interface{}{"proxy": "socks5://127.0.0.1:1080"}, &Network{Proxy: "socks5://127.0.0.1:1080"}, ""},
+		{"invalid proxy", map[string]interface{}{"proxy": "proxy.corp:3128"}, nil, "invalid proxy"},
+		{"non-string field", map[string]interface{}{"ca_file": 1.0}, nil, "ca_file must be a string"},
+		{"non-bool insecure", map[string]interface{}{"insecure_skip_verify": "yes"}, nil, "true or false"},
+		{"cert without key", map[string]interface{}{"client_cert": "/etc/ssl/me.crt"}, nil, "set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetNetwork(tt.provider)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GetNetwork() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetNetwork() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNetwork() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkEnv(t *testing.T) {
+	n := &Network{
+		Proxy:              "http://proxy.corp:3128",
+		CAFile:             "/etc/ssl/ca.pem",
+		ClientCert:         "/etc/ssl/me.crt",
+		ClientKey:          "/etc/ssl/me.key",
+		InsecureSkipVerify: true,
+	}
+	want := map[string]interface{}{
+		"HTTPS_PROXY":                  "http://proxy.corp:3128",
+		"HTTP_PROXY":                   "http://proxy.corp:3128",
+		"NODE_EXTRA_CA_CERTS":          "/etc/ssl/ca.pem",
+		"CLAUDE_CODE_CLIENT_CERT":      "/etc/ssl/me.crt",
+		"CLAUDE_CODE_CLIENT_KEY":       "/etc/ssl/me.key",
+		"NODE_TLS_REJECT_UNAUTHORIZED": "0",
+	}
+	if got := n.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+
+	var none *Network
+	if got := none.Env(); len(got) != 0 {
+		t.Errorf("nil Env() = %v, want empty", got)
+	}
+	if n.IsSOCKS() || none.IsSOCKS() || !(&Network{Proxy: "SOCKS5://127.0.0.1:1080"}).IsSOCKS() {
+		t.Error("IsSOCKS() should only report socks5:// proxies")
+	}
+}
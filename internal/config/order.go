@@ -0,0 +1,153 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// UnmarshalJSON decodes ccc.json and records the declaration order of the providers.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	order, err := providerOrder(data)
+	if err != nil {
+		return err
+	}
+	c.order = order
+	return nil
+}
+
+// MarshalJSON encodes ccc.json with the providers in declaration order.
+func (c *Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	return json.Marshal(struct {
+		*plain
+		Providers orderedProviders `json:"providers"`
+	}{(*plain)(c), orderedProviders{c.ProviderNames(), c.Providers}})
+}
+
+// UnmarshalJSON decodes .ccc.json and records the declaration order of the providers.
+func (p *ProjectConfig) UnmarshalJSON(data []byte) error {
+	type plain ProjectConfig
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	order, err := providerOrder(data)
+	if err != nil {
+		return err
+	}
+	p.order = order
+	return nil
+}
+
+// ProviderNames returns the provider names in the order they are declared
+// in ccc.json, followed by the providers of a project config, then any
+// provider added since loading, sorted by name.
+func (c *Config) ProviderNames() []string {
+	names := make([]string, 0, len(c.Providers))
+	seen := make(map[string]bool, len(c.Providers))
+	for _, name := range c.order {
+		if _, exists := c.Providers[name]; exists && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var added []string
+	for name := range c.Providers {
+		if !seen[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	return append(names, added...)
+}
+
+// RenameProvider renames a provider, keeping its position in the declaration
+// order, and updates the top-level aliases pointing to it.
+func (c *Config) RenameProvider(oldName, newName string) {
+	c.order = c.ProviderNames()
+	for i, name := range c.order {
+		if name == oldName {
+			c.order[i] = newName
+		}
+	}
+	c.Providers[newName] = c.Providers[oldName]
+	delete(c.Providers, oldName)
+	for alias, target := range c.Aliases {
+		if target == oldName {
+			c.Aliases[alias] = newName
+		}
+	}
+}
+
+// orderedProviders encodes a providers map as a JSON object with its keys in the given order.
+type orderedProviders struct {
+	names     []string
+	providers map[string]map[string]interface{}
+}
+
+func (o orderedProviders) MarshalJSON() ([]byte, error) {
+	if o.providers == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range o.names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.providers[name])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// providerOrder returns the keys of the "providers" object of a JSON
+// document in the order they appear, nil if there is no such object.
+func providerOrder(data []byte) ([]string, error) {
+	var fields struct {
+		Providers json.RawMessage `json:"providers"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if len(fields.Providers) == 0 || fields.Providers[0] != '{' {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(fields.Providers))
+	if _, err := dec.Token(); err != nil { // The opening brace
+		return nil, err
+	}
+	var names []string
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid provider name %v", token)
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// orderedConfig declares its providers out of alphabetical order.
+const orderedConfig = `{
+  "settings": {},
+  "current_provider": "",
+  "default_provider": "mid",
+  "providers": {
+    "zeta": {"env": {"ANTHROPIC_MODEL": "z"}},
+    "alpha": {},
+    "mid": {"extends": "zeta"}
+  }
+}`
+
+func TestProviderOrder(t *testing.T) {
+	t.Run("load keeps declaration order", func(t *testing.T) {
+		tmpDir, cleanup := setupTestDir(t)
+		defer cleanup()
+		if err := os.WriteFile(filepath.Join(tmpDir, "ccc.json"), []byte(orderedConfig), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := LoadUser()
+		if err != nil {
+			t.Fatalf("LoadUser() error = %v", err)
+		}
+		if got, want := cfg.ProviderNames(), []string{"zeta", "alpha", "mid"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ProviderNames() = %v, want %v", got, want)
+		}
+		if cfg.DefaultProvider != "mid" {
+			t.Errorf("DefaultProvider = %q, want mid", cfg.DefaultProvider)
+		}
+
+		// Added providers come last, removed ones disappear
+		cfg.Providers["beta"] = map[string]interface{}{}
+		delete(cfg.Providers, "alpha")
+		if got, want := cfg.ProviderNames(), []string{"zeta", "mid", "beta"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ProviderNames() after changes = %v, want %v", got, want)
+		}
+
+		// Renaming keeps the position
+		cfg.Aliases = map[string]string{"z": "zeta"}
+		cfg.RenameProvider("zeta", "omega")
+		if got, want := cfg.ProviderNames(), []string{"omega", "mid", "beta"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ProviderNames() after rename = %v, want %v", got, want)
+		}
+		if cfg.Aliases["z"] != "omega" {
+			t.Errorf("alias z = %q, want omega", cfg.Aliases["z"])
+		}
+
+		// Save writes the providers in the same order
+		if err := Save(cfg); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		data, err := os.ReadFile(GetConfigPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		omega, mid, beta := strings.Index(string(data), `"omega":`), strings.Index(string(data), `"mid":`), strings.Index(string(data), `"beta":`)
+		if !(omega < mid && mid < beta) {
+			t.Errorf("saved providers out of order:\n%s", data)
+		}
+		reloaded, err := LoadUser()
+		if err != nil {
+			t.Fatalf("LoadUser() error = %v", err)
+		}
+		if got, want := reloaded.ProviderNames(), []string{"omega", "mid", "beta"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("reloaded ProviderNames() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("project providers come after user providers", func(t *testing.T) {
+		tmpDir, cleanup := setupTestDir(t)
+		defer cleanup()
+		if err := os.WriteFile(filepath.Join(tmpDir, "ccc.json"), []byte(orderedConfig), 0644); err != nil {
+			t.Fatal(err)
+		}
+		projectDir := t.TempDir()
+		project := `{"providers": {"eu": {}, "alpha": {"env": {"ANTHROPIC_MODEL": "a"}}, "ca": {}}}`
+		if err := os.WriteFile(filepath.Join(projectDir, ProjectConfigName), []byte(project), 0644); err != nil {
+			t.Fatal(err)
+		}
+		trustProject(t, filepath.Join(projectDir, ProjectConfigName))
+		originalWorkDir := GetWorkDirFunc
+		GetWorkDirFunc = func() (string, error) { return projectDir, nil }
+		defer func() { GetWorkDirFunc = originalWorkDir }()
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if got, want := cfg.ProviderNames(), []string{"zeta", "alpha", "mid", "eu", "ca"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ProviderNames() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("config built in code is sorted", func(t *testing.T) {
+		cfg := &Config{Providers: map[string]map[string]interface{}{"b": {}, "c": {}, "a": {}}}
+		if got, want := cfg.ProviderNames(), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ProviderNames() = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitPath splits a dotted path such as "env.ANTHROPIC_MODEL" into its keys.
+func splitPath(path string) ([]string, error) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("invalid path '%s'", path)
+		}
+	}
+	return keys, nil
+}
+
+// GetPath returns the value at a dotted path such as "env.ANTHROPIC_MODEL".
+func GetPath(m map[string]interface{}, path string) (interface{}, bool) {
+	keys, err := splitPath(path)
+	if err != nil {
+		return nil, false
+	}
+	var value interface{} = m
+	for _, key := range keys {
+		current, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = current[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// SetPath sets the value at a dotted path, creating intermediate objects.
+// It fails if an intermediate value exists and is not an object.
+func SetPath(m map[string]interface{}, path string, value interface{}) error {
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+	current := m
+	for i, key := range keys[:len(keys)-1] {
+		next, exists := current[key]
+		if !exists {
+			child := make(map[string]interface{})
+			current[key] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cannot set '%s': '%s' is not an object", path, strings.Join(keys[:i+1], "."))
+		}
+		current = child
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+// DeletePath removes the value at a dotted path.
+// Objects left empty by the removal are removed as well.
+// It reports whether a value was removed.
+func DeletePath(m map[string]interface{}, path string) bool {
+	keys, err := splitPath(path)
+	if err != nil {
+		return false
+	}
+	return deletePath(m, keys)
+}
+
+func deletePath(m map[string]interface{}, keys []string) bool {
+	if len(keys) == 1 {
+		_, exists := m[keys[0]]
+		delete(m, keys[0])
+		return exists
+	}
+	child, ok := m[keys[0]].(map[string]interface{})
+	if !ok || !deletePath(child, keys[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(m, keys[0])
+	}
+	return true
+}
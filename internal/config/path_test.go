@@ -0,0 +1,80 @@
+package config
+
+import "testing"
+
+func TestPath(t *testing.T) {
+	m := map[string]interface{}{
+		"env": map[string]interface{}{"ANTHROPIC_MODEL": "kimi-k2"},
+	}
+
+	if v, ok := GetPath(m, "env.ANTHROPIC_MODEL"); !ok || v != "kimi-k2" {
+		t.Errorf("GetPath() = %v, %v", v, ok)
+	}
+	for _, path := range []string{"env.MISSING", "env.ANTHROPIC_MODEL.x", "", "env..x"} {
+		if _, ok := GetPath(m, path); ok {
+			t.Errorf("GetPath(%q) should not find a value", path)
+		}
+	}
+
+	if err := SetPath(m, "permissions.allow", []interface{}{"*"}); err != nil {
+		t.Fatalf("SetPath() error = %v", err)
+	}
+	if _, ok := GetPath(m, "permissions.allow"); !ok {
+		t.Error("SetPath() should create intermediate objects")
+	}
+	if err := SetPath(m, "env.ANTHROPIC_MODEL.x", "y"); err == nil {
+		t.Error("SetPath() should fail when an intermediate value is not an object")
+	}
+	if err := SetPath(m, "env.", "y"); err == nil {
+		t.Error("SetPath() should fail for an invalid path")
+	}
+
+	if !DeletePath(m, "permissions.allow") {
+		t.Error("DeletePath() should report the removal")
+	}
+	if _, ok := m["permissions"]; ok {
+		t.Error("DeletePath() should remove objects left empty")
+	}
+	if DeletePath(m, "env.MISSING") {
+		t.Error("DeletePath() should report missing values")
+	}
+	if _, ok := m["env"]; !ok {
+		t.Error("DeletePath() should keep non-empty objects")
+	}
+}
+
+func TestCheckProvider(t *testing.T) {
+	cfg := &Config{
+		Providers: map[string]map[string]interface{}{
+			"kimi":     {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "https://api.moonshot.cn/anthropic"}},
+			"expanded": {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "${KIMI_URL}"}},
+			"ftp":      {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": "ftp://example.com"}},
+			"orphan":   {"extends": "missing"},
+			"auto":     {"failover": []interface{}{"kimi", "expanded"}},
+			"empty":    {"failover": []interface{}{}},
+			"dangling": {"failover": []interface{}{"kimi", "missing"}},
+			"nested":   {"failover": []interface{}{"auto"}},
+		},
+	}
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"kimi", false},
+		{"expanded", false},
+		{"auto", false},
+		{"ftp", true},
+		{"orphan", true},
+		{"empty", true},
+		{"dangling", true},
+		{"nested", true},
+		{"missing", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckProvider(cfg, tt.name); (err != nil) != tt.wantErr {
+				t.Errorf("CheckProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
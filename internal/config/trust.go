@@ -0,0 +1,51 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// projectDigest returns the digest recorded in trusted_projects for the
+// content of a project config.
+func projectDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// isTrusted reports whether data is the content of the project config at
+// path that was trusted with TrustProject.
+func (c *Config) isTrusted(path string, data []byte) bool {
+	digest, ok := c.TrustedProjects[path]
+	return ok && digest == projectDigest(data)
+}
+
+// TrustProject records the current content of the project config at path
+// in the trusted_projects of ccc.json, so that Load applies it until the
+// file changes. A project config that does not parse is not trusted.
+func TrustProject(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve project config path: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read project config file: %w", err)
+	}
+	if _, err := parseProject(path, data); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(GetConfigPath()); err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	return UpdateConfig(func(cfg *Config) error {
+		if cfg.TrustedProjects == nil {
+			cfg.TrustedProjects = make(map[string]string)
+		}
+		cfg.TrustedProjects[path] = projectDigest(data)
+		return nil
+	})
+}
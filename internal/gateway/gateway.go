@@ -0,0 +1,319 @@
+// Package gateway implements a local Anthropic-compatible proxy that fronts
+// several providers and fails over between them.
+package gateway
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// maxRequestBody limits the size of a buffered client request.
+// The body must be buffered so that it can be replayed on failover.
+const maxRequestBody = 64 << 20
+
+// Upstream is a provider endpoint the gateway can forward requests to.
+type Upstream struct {
+	Name           string
+	BaseURL        string
+	AuthToken      string            // ANTHROPIC_AUTH_TOKEN, sent in the AuthStyle
+	APIKey         string            // ANTHROPIC_API_KEY, sent as x-api-key
+	AuthStyle      string            // config.AuthStyleBearer (default) or config.AuthStyleAPIKey
+	Headers        map[string]string // Custom headers, overriding the client's
+	Model          string
+	SmallFastModel string
+	// Client sends the requests to this upstream, e.g. through its proxy
+	// or with its CA file. Nil means Gateway.Client.
+	Client *http.Client
+}
+
+// Gateway forwards /v1/* requests to the first upstream that answers.
+// Upstreams are tried in order; connection errors, HTTP 5xx and HTTP 429
+// move on to the next upstream. Once an upstream has started responding,
+// its response (including SSE streams) is passed through unchanged.
+type Gateway struct {
+	// Upstreams are tried in order for every request.
+	Upstreams []Upstream
+	// Token is the credential clients must present via x-api-key or
+	// Authorization: Bearer. Empty disables the check.
+	Token string
+	// Client is the HTTP client used for upstream requests.
+	Client *http.Client
+	// Logf receives failover diagnostics. Nil disables logging.
+	Logf func(format string, args ...interface{})
+}
+
+// New creates a gateway for the given upstreams.
+func New(upstreams []Upstream, token string) *Gateway {
+	return &Gateway{
+		Upstreams: upstreams,
+		Token:     token,
+		Client: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+	}
+}
+
+// GenerateToken returns a random token suitable for Gateway.Token.
+func GenerateToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate gateway token: %w", err)
+	}
+	return "ccc-gw-" + hex.EncodeToString(buf), nil
+}
+
+// Server is a running gateway.
+type Server struct {
+	// URL is the base URL clients should use as ANTHROPIC_BASE_URL.
+	URL      string
+	listener net.Listener
+	server   *http.Server
+}
+
+// Start listens on addr (e.g. "127.0.0.1:0") and serves the gateway in the
+// background until Close is called.
+func (g *Gateway) Start(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s := &Server{
+		URL:      "http://" + listener.Addr().String(),
+		listener: listener,
+		server:   &http.Server{Handler: g},
+	}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// ServeHTTP implements http.Handler.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeError(w, http.StatusNotFound, "not_found_error", "unsupported path: "+r.URL.Path)
+		return
+	}
+	if !g.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "authentication_error", "invalid gateway token")
+		return
+	}
+	if len(g.Upstreams) == 0 {
+		writeError(w, http.StatusBadGateway, "api_error", "no upstream providers configured")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "failed to read request body")
+		return
+	}
+	if len(body) > maxRequestBody {
+		writeError(w, http.StatusRequestEntityTooLarge, "request_too_large", "request body too large")
+		return
+	}
+
+	var failures []string
+	for i, upstream := range g.Upstreams {
+		last := i == len(g.Upstreams)-1
+		resp, err := g.forward(r, upstream, g.rewriteModel(body, i))
+		if err != nil {
+			if r.Context().Err() != nil {
+				// Client went away, nothing left to answer.
+				return
+			}
+			g.logf("gateway: %s: %v", upstream.Name, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", upstream.Name, err))
+			continue
+		}
+		if shouldFailover(resp.StatusCode) && !last {
+			g.logf("gateway: %s: HTTP %d, trying next provider", upstream.Name, resp.StatusCode)
+			failures = append(failures, fmt.Sprintf("%s: HTTP %d", upstream.Name, resp.StatusCode))
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			continue
+		}
+		copyResponse(w, resp)
+		resp.Body.Close()
+		return
+	}
+
+	writeError(w, http.StatusBadGateway, "api_error", "all providers failed: "+strings.Join(failures, "; "))
+}
+
+// authorized checks the client credentials against the gateway token. Either
+// header may carry it: a client with an API key or apiKeyHelper configured
+// sends x-api-key next to the Authorization: Bearer gateway token.
+func (g *Gateway) authorized(r *http.Request) bool {
+	if g.Token == "" {
+		return true
+	}
+	for _, presented := range []string{
+		r.Header.Get("x-api-key"),
+		strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+	} {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(g.Token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// forward sends the request to a single upstream.
+func (g *Gateway) forward(r *http.Request, upstream Upstream, body []byte) (*http.Response, error) {
+	target := strings.TrimSuffix(upstream.BaseURL, "/") + r.URL.Path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.Header {
+		if skipRequestHeader(key) {
+			continue
+		}
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	// Credentials are sent the way Claude Code would send them to the upstream
+	if upstream.AuthToken != "" {
+		if upstream.AuthStyle == config.AuthStyleAPIKey {
+			req.Header.Set("x-api-key", upstream.AuthToken)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+upstream.AuthToken)
+		}
+	}
+	if upstream.APIKey != "" {
+		req.Header.Set("x-api-key", upstream.APIKey)
+	}
+	for name, value := range upstream.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if upstream.Client != nil {
+		return upstream.Client.Do(req)
+	}
+	return g.Client.Do(req)
+}
+
+// rewriteModel maps the model requested by the client onto the model of the
+// upstream at index i. Clients are configured with the first upstream's
+// models, so requests to it are forwarded unchanged; for fallback upstreams
+// the main and small/fast models are translated to their counterparts.
+func (g *Gateway) rewriteModel(body []byte, i int) []byte {
+	if i == 0 || len(body) == 0 {
+		return body
+	}
+	primary, upstream := g.Upstreams[0], g.Upstreams[i]
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	var requested string
+	if err := json.Unmarshal(payload["model"], &requested); err != nil {
+		return body
+	}
+
+	target := upstream.Model
+	if requested != "" && requested == primary.SmallFastModel && upstream.SmallFastModel != "" {
+		target = upstream.SmallFastModel
+	}
+	if target == "" || target == requested {
+		return body
+	}
+
+	encoded, err := json.Marshal(target)
+	if err != nil {
+		return body
+	}
+	payload["model"] = encoded
+	rewritten, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return rewritten
+}
+
+func (g *Gateway) logf(format string, args ...interface{}) {
+	if g.Logf != nil {
+		g.Logf(format, args...)
+	}
+}
+
+// shouldFailover reports whether a response status warrants trying the next upstream.
+func shouldFailover(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// skipRequestHeader reports whether a client header must not be forwarded.
+// Credentials are replaced per upstream; hop-by-hop headers are connection specific.
+func skipRequestHeader(key string) bool {
+	switch http.CanonicalHeaderKey(key) {
+	case "Authorization", "X-Api-Key", "Host", "Content-Length",
+		"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade":
+		return true
+	}
+	return false
+}
+
+// copyResponse writes the upstream response to the client, flushing as data
+// arrives so that server-sent events are streamed without buffering.
+func copyResponse(w http.ResponseWriter, resp *http.Response) {
+	for key, values := range resp.Header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Content-Length", "Connection", "Keep-Alive", "Transfer-Encoding":
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeError writes an Anthropic-style error response.
+func writeError(w http.ResponseWriter, status int, errType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"type": "error",
+		"error": map[string]string{
+			"type":    errType,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,289 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// upstreamRecorder is a fake provider endpoint that records the requests it receives.
+type upstreamRecorder struct {
+	server *httptest.Server
+	calls  int
+	auth   string
+	header http.Header
+	model  string
+}
+
+func newUpstream(t *testing.T, status int, body string) *upstreamRecorder {
+	t.Helper()
+	rec := &upstreamRecorder{}
+	rec.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.calls++
+		rec.auth = r.Header.Get("Authorization")
+		rec.header = r.Header.Clone()
+		var payload struct {
+			Model string `json:"model"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &payload)
+		rec.model = payload.Model
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(rec.server.Close)
+	return rec
+}
+
+func sendMessage(t *testing.T, gw *Gateway, token, model string) *http.Response {
+	t.Helper()
+	server := httptest.NewServer(gw)
+	t.Cleanup(server.Close)
+
+	body := `{"model":"` + model + `","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`
+	req, err := http.NewRequest("POST", server.URL+"/v1/messages", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("x-api-key", token)
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("anthropic-beta", "client-beta")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request error = %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestGatewayFailover(t *testing.T) {
+	tests := []struct {
+		name          string
+		primaryStatus int
+		wantFallback  bool
+	}{
+		{"success stays on primary", http.StatusOK, false},
+		{"server error fails over", http.StatusInternalServerError, true},
+		{"overloaded fails over", 529, true},
+		{"rate limit fails over", http.StatusTooManyRequests, true},
+		{"client error does not fail over", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newUpstream(t, tt.primaryStatus, `{"from":"primary"}`)
+			fallback := newUpstream(t, http.StatusOK, `{"from":"fallback"}`)
+
+			gw := New([]Upstream{
+				{Name: "kimi", BaseURL: primary.server.URL, AuthToken: "sk-kimi"},
+				{Name: "glm", BaseURL: fallback.server.URL + "/", AuthToken: "sk-glm"},
+			}, "gw-token")
+
+			resp := sendMessage(t, gw, "gw-token", "kimi-k2")
+			data, _ := io.ReadAll(resp.Body)
+
+			if tt.wantFallback {
+				if !strings.Contains(string(data), "fallback") {
+					t.Errorf("response = %s, want fallback response", data)
+				}
+				if fallback.auth != "Bearer sk-glm" {
+					t.Errorf("fallback Authorization = %q, want upstream token", fallback.auth)
+				}
+			} else {
+				if resp.StatusCode != tt.primaryStatus {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.primaryStatus)
+				}
+				if fallback.calls != 0 {
+					t.Errorf("fallback called %d times, want 0", fallback.calls)
+				}
+				if primary.auth != "Bearer sk-kimi" {
+					t.Errorf("primary Authorization = %q, want upstream token", primary.auth)
+				}
+			}
+		})
+	}
+}
+
+func TestGatewayCredentials(t *testing.T) {
+	tests := []struct {
+		name       string
+		upstream   Upstream
+		wantAuth   string
+		wantAPIKey string
+	}{
+		{"auth token", Upstream{AuthToken: "sk-token"}, "Bearer sk-token", ""},
+		{"API key", Upstream{APIKey: "sk-key"}, "", "sk-key"},
+		{"apiKeyHelper output in both headers", Upstream{AuthToken: "sk-helper", APIKey: "sk-helper"}, "Bearer sk-helper", "sk-helper"},
+		{"x-api-key auth style", Upstream{AuthToken: "sk-token", AuthStyle: config.AuthStyleAPIKey}, "", "sk-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newUpstream(t, http.StatusOK, `{}`)
+			tt.upstream.Name, tt.upstream.BaseURL = "kimi", upstream.server.URL
+			gw := New([]Upstream{tt.upstream}, "gw-token")
+
+			sendMessage(t, gw, "gw-token", "m")
+			if upstream.auth != tt.wantAuth || upstream.header.Get("x-api-key") != tt.wantAPIKey {
+				t.Errorf("Authorization = %q, x-api-key = %q, want %q and %q",
+					upstream.auth, upstream.header.Get("x-api-key"), tt.wantAuth, tt.wantAPIKey)
+			}
+		})
+	}
+}
+
+func TestGatewayHeaders(t *testing.T) {
+	upstream := newUpstream(t, http.StatusOK, `{}`)
+	gw := New([]Upstream{{
+		Name: "corp", BaseURL: upstream.server.URL, AuthToken: "sk-corp",
+		Headers: map[string]string{"X-Tenant": "team-a", "Anthropic-Beta": "corp-beta"},
+	}}, "")
+
+	sendMessage(t, gw, "", "m")
+	if got := upstream.header.Get("X-Tenant"); got != "team-a" {
+		t.Errorf("X-Tenant = %q, want team-a", got)
+	}
+	// Provider headers replace the client's
+	if got := upstream.header.Values("Anthropic-Beta"); len(got) != 1 || got[0] != "corp-beta" {
+		t.Errorf("Anthropic-Beta = %q, want [corp-beta]", got)
+	}
+}
+
+// roundTripFunc is an http.RoundTripper calling a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestGatewayUpstreamClient(t *testing.T) {
+	upstream := newUpstream(t, http.StatusOK, `{}`)
+	used := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		used++
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	gw := New([]Upstream{{Name: "corp", BaseURL: upstream.server.URL, Client: client}}, "")
+
+	resp := sendMessage(t, gw, "", "m")
+	if resp.StatusCode != http.StatusOK || used != 1 || upstream.calls != 1 {
+		t.Errorf("status %d, upstream client used %d times, upstream called %d times", resp.StatusCode, used, upstream.calls)
+	}
+}
+
+func TestGatewayConnectError(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+	fallback := newUpstream(t, http.StatusOK, `{"from":"fallback"}`)
+
+	gw := New([]Upstream{
+		{Name: "down", BaseURL: downURL},
+		{Name: "glm", BaseURL: fallback.server.URL},
+	}, "")
+
+	resp := sendMessage(t, gw, "", "m")
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if fallback.calls != 1 {
+		t.Errorf("fallback calls = %d, want 1", fallback.calls)
+	}
+}
+
+func TestGatewayAllFail(t *testing.T) {
+	first := newUpstream(t, http.StatusServiceUnavailable, `{"from":"first"}`)
+	last := newUpstream(t, http.StatusBadGateway, `{"from":"last"}`)
+
+	gw := New([]Upstream{
+		{Name: "first", BaseURL: first.server.URL},
+		{Name: "last", BaseURL: last.server.URL},
+	}, "")
+
+	resp := sendMessage(t, gw, "", "m")
+	data, _ := io.ReadAll(resp.Body)
+	// The last provider's response is passed through as-is
+	if resp.StatusCode != http.StatusBadGateway || !strings.Contains(string(data), "last") {
+		t.Errorf("got %d %s, want last provider's 502 response", resp.StatusCode, data)
+	}
+}
+
+func TestGatewayModelRewrite(t *testing.T) {
+	primary := newUpstream(t, http.StatusInternalServerError, `{}`)
+	fallback := newUpstream(t, http.StatusOK, `{}`)
+
+	gw := New([]Upstream{
+		{Name: "kimi", BaseURL: primary.server.URL, Model: "kimi-k2", SmallFastModel: "kimi-fast"},
+		{Name: "glm", BaseURL: fallback.server.URL, Model: "glm-4.7", SmallFastModel: "glm-air"},
+	}, "")
+
+	sendMessage(t, gw, "", "kimi-k2")
+	if primary.model != "kimi-k2" {
+		t.Errorf("primary model = %q, want unchanged kimi-k2", primary.model)
+	}
+	if fallback.model != "glm-4.7" {
+		t.Errorf("fallback model = %q, want glm-4.7", fallback.model)
+	}
+
+	sendMessage(t, gw, "", "kimi-fast")
+	if fallback.model != "glm-air" {
+		t.Errorf("fallback small model = %q, want glm-air", fallback.model)
+	}
+}
+
+func TestGatewayAuth(t *testing.T) {
+	upstream := newUpstream(t, http.StatusOK, `{}`)
+	gw := New([]Upstream{{Name: "kimi", BaseURL: upstream.server.URL}}, "gw-token")
+
+	resp := sendMessage(t, gw, "wrong", "m")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", resp.StatusCode)
+	}
+	if upstream.calls != 0 {
+		t.Error("upstream should not be called with an invalid gateway token")
+	}
+
+	// A client with an API key configured sends it next to the bearer token
+	req := httptest.NewRequest("POST", "/v1/messages", nil)
+	req.Header.Set("x-api-key", "sk-real")
+	req.Header.Set("Authorization", "Bearer gw-token")
+	if !gw.authorized(req) {
+		t.Error("authorized() = false, want the bearer gateway token accepted next to x-api-key")
+	}
+	req.Header.Set("Authorization", "Bearer sk-real")
+	if gw.authorized(req) {
+		t.Error("authorized() = true, want false without the gateway token")
+	}
+}
+
+func TestGatewayUnsupportedPath(t *testing.T) {
+	gw := New(nil, "")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest("GET", "/admin", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestGatewayStart(t *testing.T) {
+	upstream := newUpstream(t, http.StatusOK, `{"data":[]}`)
+	gw := New([]Upstream{{Name: "kimi", BaseURL: upstream.server.URL}}, "")
+
+	server, err := gw.Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/models")
+	if err != nil {
+		t.Fatalf("GET /v1/models error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
@@ -0,0 +1,358 @@
+// Package picker lets the user choose one item from a list, using an
+// interactive terminal picker (arrow keys + type-to-filter) when possible
+// and a numbered prompt otherwise.
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ErrCancelled is returned when the user cancels the selection.
+var ErrCancelled = errors.New("selection cancelled")
+
+// maxVisible is the maximum number of items shown at once by the terminal picker.
+const maxVisible = 15
+
+// Item is a selectable entry.
+type Item struct {
+	Label   string // Shown first and matched by the filter
+	Detail  string // Extra information shown after the label
+	Current bool   // Marks the item as current; it is preselected
+}
+
+// Pick asks the user to choose one of items and returns its index.
+// The interactive picker is used when stdin and stderr are terminals,
+// otherwise a numbered prompt is read from stdin.
+func Pick(title string, items []Item) (int, error) {
+	if len(items) == 0 {
+		return -1, fmt.Errorf("nothing to pick from")
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd())) {
+		return Interactive(os.Stdin, os.Stderr, title, items)
+	}
+	return Prompt(os.Stdin, os.Stderr, title, items)
+}
+
+// Interactive runs the terminal picker on in, which must be a terminal,
+// drawing on out. Up/Down (or Ctrl-P/Ctrl-N) move, typing filters,
+// Enter selects, Esc or Ctrl-C cancels.
+func Interactive(in *os.File, out io.Writer, title string, items []Item) (int, error) {
+	oldState, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return -1, fmt.Errorf("failed to set terminal raw mode: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), oldState)
+
+	s := newState(items)
+	drawn := 0
+	buf := make([]byte, 64)
+	for {
+		// Lines must not wrap, or clearing the drawing would miss rows
+		cols, _, err := term.GetSize(int(in.Fd()))
+		if err != nil {
+			cols = 0
+		}
+		drawn = s.render(out, title, drawn, cols)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			clearDrawing(out, drawn)
+			return -1, err
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			done, index, err := s.handle(k)
+			if done || err != nil {
+				clearDrawing(out, drawn)
+				return index, err
+			}
+		}
+	}
+}
+
+// Prompt prints a numbered list to w and reads the choice from r.
+// The user may enter a number or a label; an empty line selects the current item.
+func Prompt(r io.Reader, w io.Writer, title string, items []Item) (int, error) {
+	fmt.Fprintln(w, title)
+	width := labelWidth(items)
+	defaultIndex := -1
+	for i, item := range items {
+		fmt.Fprintf(w, "  %2d) %s\n", i+1, formatItem(item, width))
+		if item.Current {
+			defaultIndex = i
+		}
+	}
+
+	if defaultIndex >= 0 {
+		fmt.Fprintf(w, "Select [1-%d] (default %d): ", len(items), defaultIndex+1)
+	} else {
+		fmt.Fprintf(w, "Select [1-%d]: ", len(items))
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		if errors.Is(err, io.EOF) {
+			return -1, ErrCancelled
+		}
+		return -1, err
+	}
+
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		if defaultIndex < 0 {
+			return -1, ErrCancelled
+		}
+		return defaultIndex, nil
+	}
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(items) {
+			return -1, fmt.Errorf("invalid selection %d (expected 1-%d)", n, len(items))
+		}
+		return n - 1, nil
+	}
+	for i, item := range items {
+		if item.Label == answer {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("invalid selection '%s'", answer)
+}
+
+// keyKind identifies a decoded key press.
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keyEnter
+	keyBackspace
+	keyClear
+	keyCancel
+)
+
+// key is a decoded key press; r is set for keyRune.
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// decodeKeys decodes raw terminal input into key presses.
+// Unknown escape sequences and control characters are ignored.
+func decodeKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) >= 3 && (b[1] == '[' || b[1] == 'O'):
+			// Find the final byte of the sequence, e.g. A in ESC [ 1 ; 5 A
+			i := 2
+			for i < len(b)-1 && (b[i] < 0x40 || b[i] > 0x7e) {
+				i++
+			}
+			switch b[i] {
+			case 'A':
+				keys = append(keys, key{kind: keyUp})
+			case 'B':
+				keys = append(keys, key{kind: keyDown})
+			}
+			b = b[i+1:]
+			continue
+		case b[0] == 0x1b:
+			keys = append(keys, key{kind: keyCancel})
+		case b[0] == 0x03:
+			keys = append(keys, key{kind: keyCancel})
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, key{kind: keyEnter})
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, key{kind: keyBackspace})
+		case b[0] == 0x15:
+			keys = append(keys, key{kind: keyClear})
+		case b[0] == 0x10:
+			keys = append(keys, key{kind: keyUp})
+		case b[0] == 0x0e:
+			keys = append(keys, key{kind: keyDown})
+		case b[0] >= 0x20:
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError && unicode.IsPrint(r) {
+				keys = append(keys, key{kind: keyRune, r: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// state holds the picker's filter and cursor.
+type state struct {
+	items   []Item
+	query   string
+	matches []int // Indexes into items that match query
+	cursor  int   // Position in matches
+	last    int   // Last selected item, restored when a filter matches it again
+}
+
+// newState creates the picker state with the current item preselected.
+func newState(items []Item) *state {
+	s := &state{items: items, last: -1}
+	s.filter()
+	for i, index := range s.matches {
+		if items[index].Current {
+			s.cursor = i
+		}
+	}
+	return s
+}
+
+// filter recomputes matches for the query, keeping the selected item if possible.
+func (s *state) filter() {
+	if s.cursor < len(s.matches) {
+		s.last = s.matches[s.cursor]
+	}
+
+	query := strings.ToLower(s.query)
+	s.matches = s.matches[:0]
+	s.cursor = 0
+	for i, item := range s.items {
+		if strings.Contains(strings.ToLower(item.Label), query) {
+			if i == s.last {
+				s.cursor = len(s.matches)
+			}
+			s.matches = append(s.matches, i)
+		}
+	}
+}
+
+// handle applies a key press. It returns done with the chosen item index
+// on Enter, or ErrCancelled on cancel.
+func (s *state) handle(k key) (bool, int, error) {
+	switch k.kind {
+	case keyUp:
+		if s.cursor > 0 {
+			s.cursor--
+		}
+	case keyDown:
+		if s.cursor < len(s.matches)-1 {
+			s.cursor++
+		}
+	case keyEnter:
+		if len(s.matches) > 0 {
+			return true, s.matches[s.cursor], nil
+		}
+	case keyCancel:
+		return true, -1, ErrCancelled
+	case keyBackspace:
+		if s.query != "" {
+			_, size := utf8.DecodeLastRuneInString(s.query)
+			s.query = s.query[:len(s.query)-size]
+			s.filter()
+		}
+	case keyClear:
+		s.query = ""
+		s.filter()
+	case keyRune:
+		s.query += string(k.r)
+		s.filter()
+	}
+	return false, -1, nil
+}
+
+// render draws the picker, replacing the previous drawing of drawn lines.
+// Lines are truncated to cols columns, zero meaning no limit.
+// It returns the number of lines drawn.
+func (s *state) render(out io.Writer, title string, drawn, cols int) int {
+	var b strings.Builder
+	if drawn > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", drawn)
+	}
+	b.WriteString("\r\x1b[J")
+
+	fmt.Fprintf(&b, "%s\r\n", truncate(title+" (type to filter, Enter to select, Esc to cancel)", cols))
+	fmt.Fprintf(&b, "%s\r\n", truncate("> "+s.query, cols))
+	lines := 2
+
+	if len(s.matches) == 0 {
+		b.WriteString("  (no matches)\r\n")
+		lines++
+	}
+
+	// Scroll so that the cursor stays visible
+	start := 0
+	if s.cursor >= maxVisible {
+		start = s.cursor - maxVisible + 1
+	}
+	end := min(start+maxVisible, len(s.matches))
+	width := labelWidth(s.items)
+	// Items keep at least one column on narrow terminals, for the "…"
+	itemCols := 0
+	if cols > 0 {
+		itemCols = max(cols-len("> "), 1)
+	}
+	for i := start; i < end; i++ {
+		prefix := "  "
+		if i == s.cursor {
+			prefix = "\x1b[7m> "
+		}
+		fmt.Fprintf(&b, "%s%s\x1b[0m\r\n", prefix, truncate(formatItem(s.items[s.matches[i]], width), itemCols))
+		lines++
+	}
+
+	io.WriteString(out, b.String())
+	return lines
+}
+
+// clearDrawing removes a drawing of drawn lines.
+func clearDrawing(out io.Writer, drawn int) {
+	if drawn > 0 {
+		fmt.Fprintf(out, "\x1b[%dA\r\x1b[J", drawn)
+	}
+}
+
+// labelWidth returns the width of the longest label, for column alignment.
+func labelWidth(items []Item) int {
+	width := 0
+	for _, item := range items {
+		width = max(width, displayWidth(item.Label))
+	}
+	return width
+}
+
+// truncate shortens line to at most cols terminal columns, ending it with
+// "…" if it was cut. Zero cols means no limit.
+func truncate(line string, cols int) string {
+	if cols <= 0 || displayWidth(line) <= cols {
+		return line
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		w := runeWidth(r)
+		if width+w > cols-1 {
+			break
+		}
+		b.WriteRune(r)
+		width += w
+	}
+	return b.String() + "…"
+}
+
+// formatItem formats an item as an aligned line.
+func formatItem(item Item, width int) string {
+	line := item.Label
+	if item.Detail != "" {
+		line += strings.Repeat(" ", width-displayWidth(item.Label)) + "  " + item.Detail
+	}
+	if item.Current {
+		line += " (current)"
+	}
+	return line
+}
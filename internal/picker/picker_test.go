@@ -0,0 +1,199 @@
+package picker
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testItems = []Item{
+	{Label: "kimi", Detail: "https://api.moonshot.cn/anthropic  kimi-k2"},
+	{Label: "glm", Detail: "https://open.bigmodel.cn/api/anthropic  glm-4.7", Current: true},
+	{Label: "kimi-backup"},
+}
+
+func TestDecodeKeys(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []key
+	}{
+		{"arrows", "\x1b[A\x1b[B", []key{{kind: keyUp}, {kind: keyDown}}},
+		{"application mode arrows", "\x1bOA", []key{{kind: keyUp}}},
+		{"modified arrow", "\x1b[1;5B", []key{{kind: keyDown}}},
+		{"unknown sequence ignored", "\x1b[C", nil},
+		{"lone escape cancels", "\x1b", []key{{kind: keyCancel}}},
+		{"ctrl-c cancels", "\x03", []key{{kind: keyCancel}}},
+		{"enter", "\r", []key{{kind: keyEnter}}},
+		{"typing", "ké", []key{{kind: keyRune, r: 'k'}, {kind: keyRune, r: 'é'}}},
+		{"editing", "\x7f\x15", []key{{kind: keyBackspace}, {kind: keyClear}}},
+		{"emacs movement", "\x10\x0e", []key{{kind: keyUp}, {kind: keyDown}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeKeys([]byte(tt.input))
+			if len(got) != len(tt.want) {
+				t.Fatalf("decodeKeys(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("decodeKeys(%q)[%d] = %v, want %v", tt.input, i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// press feeds keys to the state and returns the final handle result.
+func press(s *state, input string) (bool, int, error) {
+	for _, k := range decodeKeys([]byte(input)) {
+		if done, index, err := s.handle(k); done || err != nil {
+			return done, index, err
+		}
+	}
+	return false, -1, nil
+}
+
+func TestStateHandle(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantDone  bool
+		wantIndex int
+		wantErr   error
+	}{
+		{"enter selects current", "\r", true, 1, nil},
+		{"up moves", "\x1b[A\r", true, 0, nil},
+		{"up stops at top", "\x1b[A\x1b[A\x1b[A\r", true, 0, nil},
+		{"down stops at bottom", "\x1b[B\x1b[B\x1b[B\r", true, 2, nil},
+		{"filter", "back\r", true, 2, nil},
+		{"filter is case insensitive", "GLM\r", true, 1, nil},
+		{"filter keeps selection", "i\r", true, 0, nil},
+		{"backspace widens filter", "kimix\x7f\x7f\x7f\x7f\x7f\x1b[B\r", true, 1, nil},
+		{"clear filter", "zzz\x15\r", true, 1, nil},
+		{"no matches ignores enter", "zzz\r", false, -1, nil},
+		{"escape cancels", "\x1b", true, -1, ErrCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done, index, err := press(newState(testItems), tt.input)
+			if done != tt.wantDone || index != tt.wantIndex || !errors.Is(err, tt.wantErr) {
+				t.Errorf("got (%v, %d, %v), want (%v, %d, %v)", done, index, err, tt.wantDone, tt.wantIndex, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	var out bytes.Buffer
+	s := newState(testItems)
+	lines := s.render(&out, "Select a provider", 0, 0)
+	if lines != 5 {
+		t.Errorf("render() drew %d lines, want 5", lines)
+	}
+	got := out.String()
+	if !strings.Contains(got, "glm ") || !strings.Contains(got, "(current)") {
+		t.Errorf("render() output missing current item: %q", got)
+	}
+
+	out.Reset()
+	press(s, "zzz")
+	if lines := s.render(&out, "Select a provider", lines, 0); lines != 3 {
+		t.Errorf("render() drew %d lines, want 3", lines)
+	}
+	if !strings.HasPrefix(out.String(), "\x1b[5A") {
+		t.Errorf("render() should move up over the previous drawing: %q", out.String())
+	}
+}
+
+func TestRenderTruncates(t *testing.T) {
+	items := []Item{
+		{Label: "kimi", Detail: "api.moonshot.cn  kimi-k2-thinking-turbo-preview", Current: true},
+		{Label: "glm", Detail: "open.bigmodel.cn  glm-4.7"},
+	}
+	var out bytes.Buffer
+	s := newState(items)
+	if lines := s.render(&out, "Select a provider", 0, 20); lines != 4 {
+		t.Errorf("render() drew %d lines, want 4", lines)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n") {
+		line = strings.NewReplacer("\r\x1b[J", "", "\x1b[7m", "", "\x1b[0m", "").Replace(line)
+		if n := displayWidth(line); n > 20 {
+			t.Errorf("line %q is %d columns wide, want at most 20", line, n)
+		}
+	}
+	if !strings.Contains(out.String(), "\x1b[7m> kimi") || !strings.Contains(out.String(), "…") {
+		t.Errorf("render() should highlight and truncate the current item: %q", out.String())
+	}
+	if got := truncate("kimi", 0); got != "kimi" {
+		t.Errorf("truncate(kimi, 0) = %q, want kimi", got)
+	}
+
+	// Items keep a minimum width on terminals narrower than the prefix
+	out.Reset()
+	s.render(&out, "Select a provider", 0, 1)
+	if !strings.Contains(out.String(), "\x1b[7m> …\x1b[0m") {
+		t.Errorf("render() on a 1 column terminal = %q, want items truncated to …", out.String())
+	}
+}
+
+func TestTruncateWide(t *testing.T) {
+	tests := []struct {
+		line string
+		cols int
+		want string
+	}{
+		{"智谱清言 glm", 8, "智谱清…"},
+		{"智谱清言", 8, "智谱清言"},
+		{"🚀 fast provider", 6, "🚀 fa…"},
+		{"kimi 🚀", 7, "kimi 🚀"},
+		{"café", 4, "café"},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.line, tt.cols)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.line, tt.cols, got, tt.want)
+		}
+		if w := displayWidth(got); w > tt.cols {
+			t.Errorf("truncate(%q, %d) is %d columns wide", tt.line, tt.cols, w)
+		}
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		items     []Item
+		wantIndex int
+		wantErr   bool
+	}{
+		{"number", "1\n", testItems, 0, false},
+		{"label", "kimi-backup\n", testItems, 2, false},
+		{"empty selects current", "\n", testItems, 1, false},
+		{"empty without current", "\n", []Item{{Label: "a"}}, -1, true},
+		{"out of range", "9\n", testItems, -1, true},
+		{"unknown label", "openai\n", testItems, -1, true},
+		{"eof", "", testItems, -1, true},
+		{"no trailing newline", "3", testItems, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			index, err := Prompt(strings.NewReader(tt.input), &out, "Select a provider:", tt.items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Prompt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if index != tt.wantIndex {
+				t.Errorf("Prompt() = %d, want %d", index, tt.wantIndex)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	Prompt(strings.NewReader("\n"), &out, "Select a provider:", testItems)
+	if !strings.Contains(out.String(), " 2) glm          https://open.bigmodel.cn/api/anthropic  glm-4.7 (current)") {
+		t.Errorf("Prompt() output not aligned:\n%s", out.String())
+	}
+}
@@ -0,0 +1,145 @@
+// Package preset provides the catalog of known provider presets: base URL,
+// default models and required headers for common Anthropic-compatible vendors.
+package preset
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// BuiltinSource is the Source of presets shipped with ccc.
+const BuiltinSource = "builtin"
+
+//go:embed presets.json
+var builtinData []byte
+
+// Preset describes how to reach a vendor's Anthropic-compatible API.
+type Preset struct {
+	Name           string            `json:"-"`
+	Description    string            `json:"description,omitempty"`
+	BaseURL        string            `json:"base_url"`
+	Model          string            `json:"model,omitempty"`
+	SmallFastModel string            `json:"small_fast_model,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	AuthStyle      string            `json:"auth_style,omitempty"` // config.AuthStyleBearer (default) or config.AuthStyleAPIKey
+	Source         string            `json:"-"`                    // BuiltinSource or the file it was loaded from
+}
+
+// GetDir returns the directory holding extra preset files.
+func GetDir() string {
+	return filepath.Join(config.GetDir(), "ccc", "presets")
+}
+
+// parse decodes a preset file: a JSON object mapping preset names to presets.
+func parse(data []byte, source string) ([]Preset, error) {
+	var raw map[string]Preset
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid preset file %s: %w", source, err)
+	}
+	presets := make([]Preset, 0, len(raw))
+	for name, p := range raw {
+		if p.BaseURL == "" {
+			return nil, fmt.Errorf("invalid preset file %s: preset '%s' has no base_url", source, name)
+		}
+		if p.AuthStyle != "" {
+			if _, err := config.GetAuthStyle(map[string]interface{}{"auth_style": p.AuthStyle}); err != nil {
+				return nil, fmt.Errorf("invalid preset file %s: preset '%s': %w", source, name, err)
+			}
+		}
+		p.Name = name
+		p.Source = source
+		presets = append(presets, p)
+	}
+	return presets, nil
+}
+
+// Load returns the builtin presets together with the presets from *.json
+// files in GetDir(), sorted by name. A preset file overrides a builtin
+// preset of the same name; files are applied in lexical order.
+func Load() ([]Preset, error) {
+	builtin, err := parse(builtinData, BuiltinSource)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Preset, len(builtin))
+	for _, p := range builtin {
+		byName[p.Name] = p
+	}
+
+	files, err := filepath.Glob(filepath.Join(GetDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preset file: %w", err)
+		}
+		presets, err := parse(data, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range presets {
+			byName[p.Name] = p
+		}
+	}
+
+	presets := make([]Preset, 0, len(byName))
+	for _, p := range byName {
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets, nil
+}
+
+// Find returns the preset with the given name.
+func Find(name string) (*Preset, error) {
+	presets, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(presets))
+	for i := range presets {
+		if presets[i].Name == name {
+			return &presets[i], nil
+		}
+		names = append(names, presets[i].Name)
+	}
+	return nil, fmt.Errorf("preset '%s' not found (available: %s)", name, strings.Join(names, ", "))
+}
+
+// TokenEnv returns the env variable the preset's credential is stored in:
+// ANTHROPIC_API_KEY for the x-api-key auth style, which Claude Code sends as
+// x-api-key, and ANTHROPIC_AUTH_TOKEN (sent as Authorization: Bearer) otherwise.
+func (p *Preset) TokenEnv() string {
+	if p.AuthStyle == config.AuthStyleAPIKey {
+		return "ANTHROPIC_API_KEY"
+	}
+	return "ANTHROPIC_AUTH_TOKEN"
+}
+
+// Env returns the provider env for the preset, without the auth token.
+// Headers are passed to Claude Code via ANTHROPIC_CUSTOM_HEADERS.
+func (p *Preset) Env() map[string]interface{} {
+	env := map[string]interface{}{
+		"ANTHROPIC_BASE_URL": p.BaseURL,
+	}
+	if p.Model != "" {
+		env["ANTHROPIC_MODEL"] = p.Model
+	}
+	if p.SmallFastModel != "" {
+		env["ANTHROPIC_SMALL_FAST_MODEL"] = p.SmallFastModel
+	}
+	if len(p.Headers) > 0 {
+		env["ANTHROPIC_CUSTOM_HEADERS"] = config.FormatCustomHeaders(p.Headers)
+	}
+	return env
+}
@@ -0,0 +1,114 @@
+package preset
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+func setupTestDir(t *testing.T) string {
+	t.Helper()
+	original := config.GetDirFunc
+	tmpDir := t.TempDir()
+	config.GetDirFunc = func() string { return tmpDir }
+	t.Cleanup(func() { config.GetDirFunc = original })
+	return tmpDir
+}
+
+func TestLoadBuiltin(t *testing.T) {
+	setupTestDir(t)
+
+	presets, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(presets) == 0 {
+		t.Fatal("Load() returned no presets")
+	}
+	for i, p := range presets {
+		if p.Source != BuiltinSource {
+			t.Errorf("%s: Source = %q, want %q", p.Name, p.Source, BuiltinSource)
+		}
+		if !strings.HasPrefix(p.BaseURL, "https://") {
+			t.Errorf("%s: BaseURL = %q, want https URL", p.Name, p.BaseURL)
+		}
+		if i > 0 && presets[i-1].Name >= p.Name {
+			t.Errorf("presets not sorted: %s before %s", presets[i-1].Name, p.Name)
+		}
+	}
+
+	kimi, err := Find("kimi")
+	if err != nil {
+		t.Fatalf("Find(kimi) error = %v", err)
+	}
+	if kimi.BaseURL != "https://api.moonshot.cn/anthropic" {
+		t.Errorf("kimi BaseURL = %q", kimi.BaseURL)
+	}
+	if kimi.TokenEnv() != "ANTHROPIC_AUTH_TOKEN" {
+		t.Errorf("kimi TokenEnv() = %q", kimi.TokenEnv())
+	}
+	anthropic, err := Find("anthropic")
+	if err != nil {
+		t.Fatalf("Find(anthropic) error = %v", err)
+	}
+	if anthropic.TokenEnv() != "ANTHROPIC_API_KEY" {
+		t.Errorf("anthropic TokenEnv() = %q, want ANTHROPIC_API_KEY", anthropic.TokenEnv())
+	}
+	if _, err := Find("nope"); err == nil || !strings.Contains(err.Error(), "kimi") {
+		t.Errorf("Find(nope) error = %v, want not found listing presets", err)
+	}
+}
+
+func TestLoadUserPresets(t *testing.T) {
+	dir := filepath.Join(setupTestDir(t), "ccc", "presets")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{
+		"kimi": {"base_url": "https://proxy.example.com/kimi"},
+		"corp": {"base_url": "https://llm.corp.example.com", "model": "m1", "headers": {"X-Team": "infra"}}
+	}`
+	file := filepath.Join(dir, "corp.json")
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	kimi, err := Find("kimi")
+	if err != nil {
+		t.Fatalf("Find(kimi) error = %v", err)
+	}
+	if kimi.BaseURL != "https://proxy.example.com/kimi" || kimi.Source != file {
+		t.Errorf("kimi = %+v, want override from %s", kimi, file)
+	}
+
+	corp, err := Find("corp")
+	if err != nil {
+		t.Fatalf("Find(corp) error = %v", err)
+	}
+	env := corp.Env()
+	if env["ANTHROPIC_BASE_URL"] != "https://llm.corp.example.com" || env["ANTHROPIC_MODEL"] != "m1" {
+		t.Errorf("Env() = %v", env)
+	}
+	if env["ANTHROPIC_CUSTOM_HEADERS"] != "X-Team: infra" {
+		t.Errorf("ANTHROPIC_CUSTOM_HEADERS = %q", env["ANTHROPIC_CUSTOM_HEADERS"])
+	}
+	if _, ok := env["ANTHROPIC_SMALL_FAST_MODEL"]; ok {
+		t.Error("Env() should omit an empty small/fast model")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"x": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "base_url") {
+		t.Errorf("Load() error = %v, want missing base_url", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"x": {"base_url": "https://x", "auth_style": "basic"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "auth_style") {
+		t.Errorf("Load() error = %v, want unknown auth_style", err)
+	}
+}
@@ -2,12 +2,13 @@
 package provider
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/secret"
 )
 
 // EnvPair represents a single environment variable key-value pair.
@@ -19,7 +20,8 @@ type EnvPair struct {
 // SwitchResult contains the result of switching providers.
 // It includes the merged env variables that should be passed to the child process.
 type SwitchResult struct {
-	// Settings is the merged settings (without env) that was saved to settings.json
+	// Settings is the merged settings (without env) that was saved to settings.json,
+	// or, for an isolated session, that must be passed via --settings
 	Settings map[string]interface{}
 	// EnvVars contains the merged environment variables (base.env + provider.env)
 	// that should be passed to the claude subprocess
@@ -33,78 +35,239 @@ type SwitchResult struct {
 // It generates settings.json with merged configuration from:
 //  1. Existing settings.json (user config - highest priority)
 //  2. ccc.json settings (base template)
-//  3. Provider settings (provider-specific)
+//  3. Provider settings (provider-specific, with its extends chain resolved)
 //
 // It also removes any leftover supervisor artifacts (slash commands, state, logs).
+// Writes are locked read-modify-writes (config.UpdateSettings and
+// config.UpdateConfig), so concurrent ccc processes never lose changes.
 // Returns the merged env that should be passed to the claude subprocess.
 func SwitchWithHook(cfg *config.Config, providerName string) (*SwitchResult, error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config is nil")
 	}
 
-	// Check if provider exists
-	providerSettings, exists := cfg.Providers[providerName]
-	if !exists {
-		return nil, fmt.Errorf("provider '%s' not found in configuration", providerName)
+	// Resolve the provider, applying its extends chain
+	resolved, err := config.ResolveProvider(cfg, providerName)
+	if err != nil {
+		return nil, err
 	}
+	providerSettings := config.ProviderSettings(resolved)
 
-	// Load existing settings.json (user's actual configuration)
-	userSettings, err := config.LoadSettings()
+	if err := checkLaunchNetwork(providerName, resolved); err != nil {
+		return nil, err
+	}
+
+	// Extract env map for subprocess: only base + provider env (not user env).
+	// Secrets are resolved before anything is written.
+	subprocessEnvMap, err := subprocessEnv(cfg, providerName, resolved)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load settings: %w", err)
+		return nil, err
 	}
 
-	// Extract env from each source before merging (to distinguish user env from ccc env)
-	userEnvMap := config.GetEnv(userSettings)
-	baseEnvMap := config.GetEnv(cfg.Settings)
-	providerEnvMap := config.GetEnv(providerSettings)
+	// Merge with the existing settings.json (user's actual configuration)
+	// under the lock, so concurrent launches cannot interleave their writes
+	var cleanedSettings map[string]interface{}
+	err = config.UpdateSettings(func(userSettings map[string]interface{}) (map[string]interface{}, error) {
+		// Extract user env before merging (to distinguish user env from ccc env)
+		userEnvMap := config.GetEnv(userSettings)
+
+		// Merge settings with priority: user > provider > base
+		mergedSettings := config.MergeWithPriority(cfg.Settings, providerSettings, userSettings)
+
+		// Remove Supervisor Stop hook and related fields
+		cleanedSettings = config.RemoveStopHook(mergedSettings)
+
+		// Remove merged env from settings, replace with user's original env.
+		// Conflicting keys are no longer filtered here -- they are overridden
+		// by the --settings CLI parameter when launching claude (higher priority).
+		delete(cleanedSettings, "env")
+		if len(userEnvMap) > 0 {
+			cleanedSettings["env"] = userEnvMap
+		}
+		return cleanedSettings, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update settings: %w", err)
+	}
+
+	// Clean up any leftover supervisor artifacts
+	cleanupSupervisorArtifacts()
 
-	// Merge settings with priority: user > provider > base
-	mergedSettings := config.MergeWithPriority(cfg.Settings, providerSettings, userSettings)
+	// Update current_provider in ccc.json, keeping concurrent edits
+	if err := config.SaveCurrentProvider(cfg, providerName); err != nil {
+		return nil, fmt.Errorf("failed to update current provider: %w", err)
+	}
 
-	// Remove Supervisor Stop hook and related fields
-	cleanedSettings := config.RemoveStopHook(mergedSettings)
+	return &SwitchResult{
+		Settings:    cleanedSettings,
+		EnvVars:     EnvMapToPairs(subprocessEnvMap),
+		ProviderEnv: subprocessEnvMap,
+	}, nil
+}
 
-	// Remove merged env from settings, replace with user's original env.
-	// Conflicting keys are no longer filtered here -- they are overridden
-	// by the --settings CLI parameter when launching claude (higher priority).
-	delete(cleanedSettings, "env")
-	if userEnvMap != nil && len(userEnvMap) > 0 {
-		cleanedSettings["env"] = userEnvMap
+// PrepareIsolated prepares an isolated session for the specified provider
+// without writing settings.json or ccc.json. The returned Settings holds the
+// ccc-managed settings (base + provider, without env) that must be passed
+// to claude via --settings. For keys that settings.json also defines, the
+// user's values are kept so that the usual priority (user > provider > base)
+// still applies.
+func PrepareIsolated(cfg *config.Config, providerName string) (*SwitchResult, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
 	}
 
-	// Save merged settings to settings.json
-	settingsPath := config.GetSettingsPath()
-	settingsData, err := json.MarshalIndent(cleanedSettings, "", "  ")
+	resolved, err := config.ResolveProvider(cfg, providerName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal settings: %w", err)
+		return nil, err
 	}
-	if err := os.WriteFile(settingsPath, settingsData, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write settings: %w", err)
+	providerSettings := config.ProviderSettings(resolved)
+	if err := checkLaunchNetwork(providerName, resolved); err != nil {
+		return nil, err
 	}
 
-	// Clean up any leftover supervisor artifacts
-	cleanupSupervisorArtifacts()
-
-	// Update current_provider in ccc.json
-	cfg.CurrentProvider = providerName
-	if err := config.Save(cfg); err != nil {
-		return nil, fmt.Errorf("failed to update current provider: %w", err)
+	subprocessEnvMap, err := subprocessEnv(cfg, providerName, resolved)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract env map for subprocess: only base + provider env (not user env)
-	subprocessEnvMap := config.MergeEnvMaps(baseEnvMap, providerEnvMap)
+	userSettings, err := config.LoadSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
 
-	// Convert env map to EnvPair slice
-	envVars := envMapToPairs(subprocessEnvMap)
+	// Only keys managed by ccc are passed; settings.json is read by claude itself.
+	// The Supervisor Stop hook is removed as in SwitchWithHook.
+	cccSettings := config.DeepMerge(cfg.Settings, providerSettings)
+	merged := config.RemoveStopHook(config.MergeWithPriority(cfg.Settings, providerSettings, userSettings))
+	sessionSettings := make(map[string]interface{}, len(cccSettings))
+	for key := range cccSettings {
+		if value, ok := merged[key]; ok && key != "env" {
+			sessionSettings[key] = value
+		}
+	}
 
 	return &SwitchResult{
-		Settings:    cleanedSettings,
-		EnvVars:     envVars,
+		Settings:    sessionSettings,
+		EnvVars:     EnvMapToPairs(subprocessEnvMap),
 		ProviderEnv: subprocessEnvMap,
 	}, nil
 }
 
+// ProviderEnv returns the merged base + provider env for the given provider
+// without touching any files. Secret references are resolved; other values
+// are returned as configured and should be read with ExpandValue.
+func ProviderEnv(cfg *config.Config, providerName string) (map[string]interface{}, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil")
+	}
+	resolved, err := config.ResolveProvider(cfg, providerName)
+	if err != nil {
+		return nil, err
+	}
+	return subprocessEnv(cfg, providerName, resolved)
+}
+
+// checkLaunchNetwork rejects network settings that Claude Code cannot use,
+// so that a launch does not silently bypass the configured proxy.
+func checkLaunchNetwork(providerName string, resolved map[string]interface{}) error {
+	network, err := config.GetNetwork(resolved)
+	if err != nil {
+		return fmt.Errorf("provider '%s': %w", providerName, err)
+	}
+	if network.IsSOCKS() {
+		return fmt.Errorf("provider '%s': Claude Code does not support socks5:// proxies, use an http:// or https:// proxy, or run 'ccc gateway %s' and point Claude Code at it", providerName, providerName)
+	}
+	return nil
+}
+
+// subprocessEnv returns the env passed to claude for a resolved provider:
+// the base env, the env of the provider's network settings (proxy, CA file,
+// client certificate) and the provider env, in increasing priority, so an
+// explicit HTTPS_PROXY in the provider env still wins. Secrets are resolved.
+// The provider's headers and auth_style are exported as ANTHROPIC_CUSTOM_HEADERS.
+func subprocessEnv(cfg *config.Config, providerName string, resolved map[string]interface{}) (map[string]interface{}, error) {
+	network, err := config.GetNetwork(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", providerName, err)
+	}
+	envMap := config.MergeEnvMaps(config.GetEnv(cfg.Settings), network.Env(), config.GetEnv(resolved))
+	envMap, err = ResolveSecrets(providerName, envMap)
+	if err != nil {
+		return nil, err
+	}
+
+	_, hasHeaders := resolved["headers"]
+	authStyle, err := config.GetAuthStyle(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", providerName, err)
+	}
+	if !hasHeaders && authStyle == config.AuthStyleBearer {
+		return envMap, nil
+	}
+	headers, err := config.GetHeaders(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("provider '%s': %w", providerName, err)
+	}
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	// Claude Code sends ANTHROPIC_AUTH_TOKEN as a bearer token, so the
+	// x-api-key auth style is passed as a custom header
+	if token := envMap["ANTHROPIC_AUTH_TOKEN"]; authStyle == config.AuthStyleAPIKey && token != nil {
+		headers["x-api-key"] = ExpandValue(token)
+	}
+	if len(headers) > 0 {
+		if envMap == nil {
+			envMap = make(map[string]interface{})
+		}
+		// The values are already expanded and may contain the token,
+		// so they are passed on verbatim like a resolved secret
+		envMap["ANTHROPIC_CUSTOM_HEADERS"] = secret.Value(config.FormatCustomHeaders(headers))
+	}
+	return envMap, nil
+}
+
+// ResolveSecrets returns a copy of envMap with secret references such as
+// "cmd:pass show kimi" replaced by their secret.Value. ${VAR} references
+// inside a secret reference are expanded before resolving it.
+// Errors name the provider and key but never the secret.
+func ResolveSecrets(providerName string, envMap map[string]interface{}) (map[string]interface{}, error) {
+	if envMap == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(envMap))
+	for k := range envMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make(map[string]interface{}, len(envMap))
+	for _, k := range keys {
+		v := envMap[k]
+		str, ok := v.(string)
+		if !ok || !secret.IsRef(os.ExpandEnv(str)) {
+			result[k] = v
+			continue
+		}
+		value, err := secret.Resolve(os.ExpandEnv(str))
+		if err != nil {
+			return nil, fmt.Errorf("provider '%s': failed to resolve %s: %w", providerName, k, err)
+		}
+		result[k] = secret.Value(value)
+	}
+	return result, nil
+}
+
+// ExpandValue converts an env value to a string, expanding ${VAR} references.
+// Resolved secrets are returned verbatim.
+func ExpandValue(v interface{}) string {
+	if s, ok := v.(secret.Value); ok {
+		return string(s)
+	}
+	return os.ExpandEnv(fmt.Sprintf("%v", v))
+}
+
 // cleanupSupervisorArtifacts removes leftover supervisor files:
 //   - slash command files (supervisor.md, supervisoroff.md)
 //   - state files (supervisor-*.json) and log files (supervisor-*.log)
@@ -126,19 +289,16 @@ func cleanupSupervisorArtifacts() {
 	}
 }
 
-// envMapToPairs converts a map[string]interface{} to []EnvPair.
+// EnvMapToPairs converts a map[string]interface{} to []EnvPair.
 // It expands environment variable references like ${VAR}.
-func envMapToPairs(envMap map[string]interface{}) []EnvPair {
+func EnvMapToPairs(envMap map[string]interface{}) []EnvPair {
 	if envMap == nil {
 		return nil
 	}
 
 	pairs := make([]EnvPair, 0, len(envMap))
 	for k, v := range envMap {
-		value := fmt.Sprintf("%v", v)
-		// Expand environment variable references
-		value = os.ExpandEnv(value)
-		pairs = append(pairs, EnvPair{Key: k, Value: value})
+		pairs = append(pairs, EnvPair{Key: k, Value: ExpandValue(v)})
 	}
 	return pairs
 }
@@ -165,17 +325,13 @@ func FormatProviderName(name, currentProvider string) string {
 	return name
 }
 
-// ListProviders returns a list of all provider names from the config.
+// ListProviders returns a list of all provider names from the config,
+// in the order they are declared in ccc.json.
 func ListProviders(cfg *config.Config) []string {
 	if cfg == nil || len(cfg.Providers) == 0 {
 		return []string{}
 	}
-
-	names := make([]string, 0, len(cfg.Providers))
-	for name := range cfg.Providers {
-		names = append(names, name)
-	}
-	return names
+	return cfg.ProviderNames()
 }
 
 // ValidateProvider checks if a provider name exists in the config.
@@ -189,20 +345,34 @@ func ValidateProvider(cfg *config.Config, providerName string) error {
 	return nil
 }
 
-// GetDefaultProvider returns the first provider name from the config.
+// Resolve returns the provider that name refers to, which may be one of its
+// aliases (see config.ResolveAlias), or an error if there is none.
+func Resolve(cfg *config.Config, name string) (string, error) {
+	if cfg == nil {
+		return "", fmt.Errorf("config is nil")
+	}
+	name = config.ResolveAlias(cfg, name)
+	if err := ValidateProvider(cfg, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// GetDefaultProvider returns default_provider if it exists, otherwise the
+// first provider declared in the config.
 // Returns empty string if no providers are configured.
 func GetDefaultProvider(cfg *config.Config) string {
 	if cfg == nil || len(cfg.Providers) == 0 {
 		return ""
 	}
-	for name := range cfg.Providers {
-		return name
+	if _, exists := cfg.Providers[cfg.DefaultProvider]; exists {
+		return cfg.DefaultProvider
 	}
-	return ""
+	return cfg.ProviderNames()[0]
 }
 
 // GetCurrentProvider returns the current provider from config.
-// If current_provider is not set, returns the first available provider.
+// If current_provider is not set, returns the default provider.
 // Returns empty string if no providers are configured.
 func GetCurrentProvider(cfg *config.Config) string {
 	if cfg == nil {
@@ -216,7 +386,7 @@ func GetCurrentProvider(cfg *config.Config) string {
 		}
 	}
 
-	// Fall back to first provider
+	// Fall back to the default provider
 	return GetDefaultProvider(cfg)
 }
 
@@ -237,7 +407,8 @@ func ShortenError(err error, maxLength int) string {
 	return errMsg
 }
 
-// GetAuthToken extracts the ANTHROPIC_AUTH_TOKEN from merged settings.
+// GetAuthToken extracts the credential (auth token, API key or apiKeyHelper
+// output) from merged settings.
 // This is a convenience wrapper around config.GetAuthToken.
 func GetAuthToken(settings map[string]interface{}) string {
 	return config.GetAuthToken(settings)
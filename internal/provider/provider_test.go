@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/secret"
 )
 
 // setupTestConfig creates a test configuration.
@@ -196,6 +198,51 @@ func TestSwitchWithHook(t *testing.T) {
 			t.Fatal("SwitchWithHook() should error for nil config")
 		}
 	})
+
+	t.Run("provider with extends", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		cfg := setupTestConfig(t)
+		cfg.Providers["kimi-fast"] = map[string]interface{}{
+			"extends": "kimi",
+			"env": map[string]interface{}{
+				"ANTHROPIC_MODEL": "kimi-k2-turbo",
+			},
+		}
+
+		result, err := SwitchWithHook(cfg, "kimi-fast")
+		if err != nil {
+			t.Fatalf("SwitchWithHook() error = %v", err)
+		}
+
+		envMap := make(map[string]string)
+		for _, pair := range result.EnvVars {
+			envMap[pair.Key] = pair.Value
+		}
+		if envMap["ANTHROPIC_BASE_URL"] != "https://api.moonshot.cn/anthropic" {
+			t.Errorf("BASE_URL = %v, want inherited kimi URL", envMap["ANTHROPIC_BASE_URL"])
+		}
+		if envMap["ANTHROPIC_MODEL"] != "kimi-k2-turbo" {
+			t.Errorf("MODEL = %v, want kimi-k2-turbo", envMap["ANTHROPIC_MODEL"])
+		}
+		if _, exists := result.Settings["extends"]; exists {
+			t.Error("Settings should not contain 'extends' field")
+		}
+	})
+
+	t.Run("provider with extends cycle", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		cfg := setupTestConfig(t)
+		cfg.Providers["loop"] = map[string]interface{}{"extends": "loop"}
+
+		_, err := SwitchWithHook(cfg, "loop")
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Fatalf("SwitchWithHook() error = %v, want cycle error", err)
+		}
+	})
 }
 
 func TestSwitchWithHookUserEnv(t *testing.T) {
@@ -362,6 +409,176 @@ func TestSwitchWithHookUserEnv(t *testing.T) {
 	}
 }
 
+func TestPrepareIsolated(t *testing.T) {
+	t.Run("does not write any file", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		cfg := setupTestConfig(t)
+		result, err := PrepareIsolated(cfg, "glm")
+		if err != nil {
+			t.Fatalf("PrepareIsolated() error = %v", err)
+		}
+
+		for _, path := range []string{config.GetSettingsPath(), config.GetConfigPath()} {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("PrepareIsolated() should not create %s", path)
+			}
+		}
+		if cfg.CurrentProvider != "kimi" {
+			t.Errorf("CurrentProvider = %s, want kimi (unchanged)", cfg.CurrentProvider)
+		}
+
+		if result.Settings["alwaysThinkingEnabled"] != true {
+			t.Errorf("Settings should contain base settings, got %v", result.Settings)
+		}
+		if _, ok := result.Settings["env"]; ok {
+			t.Error("Settings should not contain env")
+		}
+		if result.ProviderEnv["ANTHROPIC_BASE_URL"] != "https://open.bigmodel.cn/api/anthropic" {
+			t.Errorf("ProviderEnv ANTHROPIC_BASE_URL = %v, want glm URL", result.ProviderEnv["ANTHROPIC_BASE_URL"])
+		}
+	})
+
+	t.Run("same settings as a normal switch", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		cfg := setupTestConfig(t)
+		cfg.Settings["hooks"] = map[string]interface{}{
+			"Stop": []interface{}{map[string]interface{}{"hooks": []interface{}{
+				map[string]interface{}{"type": "command", "command": "ccc supervisor-hook"},
+			}}},
+			"SessionStart": []interface{}{map[string]interface{}{"hooks": []interface{}{
+				map[string]interface{}{"type": "command", "command": "echo hi"},
+			}}},
+		}
+
+		isolated, err := PrepareIsolated(cfg, "glm")
+		if err != nil {
+			t.Fatalf("PrepareIsolated() error = %v", err)
+		}
+		switched, err := SwitchWithHook(cfg, "glm")
+		if err != nil {
+			t.Fatalf("SwitchWithHook() error = %v", err)
+		}
+		if len(isolated.Settings) == 0 {
+			t.Fatal("PrepareIsolated() returned no settings")
+		}
+		for key, value := range isolated.Settings {
+			if !reflect.DeepEqual(value, switched.Settings[key]) {
+				t.Errorf("Settings[%s] = %v, want %v as in a normal switch", key, value, switched.Settings[key])
+			}
+		}
+		if hooks, _ := isolated.Settings["hooks"].(map[string]interface{}); hooks["Stop"] != nil || hooks["SessionStart"] == nil {
+			t.Errorf("hooks = %v, want the supervisor Stop hook removed", hooks)
+		}
+	})
+
+	t.Run("user settings keep priority", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		if err := config.SaveSettings(map[string]interface{}{
+			"alwaysThinkingEnabled": false,
+			"model":                 "opus",
+		}); err != nil {
+			t.Fatalf("Failed to save user settings: %v", err)
+		}
+
+		cfg := setupTestConfig(t)
+		result, err := PrepareIsolated(cfg, "kimi")
+		if err != nil {
+			t.Fatalf("PrepareIsolated() error = %v", err)
+		}
+		if result.Settings["alwaysThinkingEnabled"] != false {
+			t.Errorf("alwaysThinkingEnabled = %v, want false (user value)", result.Settings["alwaysThinkingEnabled"])
+		}
+		if _, ok := result.Settings["model"]; ok {
+			t.Error("Settings should only contain keys managed by ccc")
+		}
+	})
+
+	t.Run("network settings", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		cfg := setupTestConfig(t)
+		cfg.Providers["kimi"]["proxy"] = "http://proxy.corp:3128"
+		cfg.Providers["kimi"]["ca_file"] = "/etc/ssl/corp-ca.pem"
+		cfg.Providers["kimi"]["env"].(map[string]interface{})["HTTP_PROXY"] = "http://other:8080"
+		result, err := PrepareIsolated(cfg, "kimi")
+		if err != nil {
+			t.Fatalf("PrepareIsolated() error = %v", err)
+		}
+		want := map[string]interface{}{
+			"HTTPS_PROXY":         "http://proxy.corp:3128",
+			"HTTP_PROXY":          "http://other:8080", // the provider env wins
+			"NODE_EXTRA_CA_CERTS": "/etc/ssl/corp-ca.pem",
+		}
+		for key, value := range want {
+			if result.ProviderEnv[key] != value {
+				t.Errorf("ProviderEnv[%s] = %v, want %v", key, result.ProviderEnv[key], value)
+			}
+		}
+		if _, ok := result.Settings["proxy"]; ok {
+			t.Error("Settings should not contain the proxy field")
+		}
+
+		cfg.Providers["kimi"]["client_cert"] = "/etc/ssl/me.crt"
+		if _, err := PrepareIsolated(cfg, "kimi"); err == nil {
+			t.Error("PrepareIsolated() should fail for client_cert without client_key")
+		}
+	})
+
+	t.Run("socks5 proxy", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		// Claude Code only supports HTTP(S) proxies, launching would bypass it
+		cfg := setupTestConfig(t)
+		cfg.Providers["kimi"]["proxy"] = "socks5://127.0.0.1:1080"
+		if _, err := PrepareIsolated(cfg, "kimi"); err == nil || !strings.Contains(err.Error(), "socks5") {
+			t.Errorf("PrepareIsolated() error = %v, want socks5 rejected", err)
+		}
+		if _, err := SwitchWithHook(cfg, "kimi"); err == nil || !strings.Contains(err.Error(), "socks5") {
+			t.Errorf("SwitchWithHook() error = %v, want socks5 rejected", err)
+		}
+		if _, err := os.Stat(config.GetSettingsPath()); !os.IsNotExist(err) {
+			t.Error("a rejected launch must not write settings.json")
+		}
+	})
+
+	t.Run("headers and auth style", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		cfg := setupTestConfig(t)
+		cfg.Providers["kimi"]["headers"] = map[string]interface{}{"X-Tenant": "team-a"}
+		cfg.Providers["kimi"]["auth_style"] = "x-api-key"
+		result, err := PrepareIsolated(cfg, "kimi")
+		if err != nil {
+			t.Fatalf("PrepareIsolated() error = %v", err)
+		}
+		want := "X-Tenant: team-a\nx-api-key: sk-kimi-xxx"
+		if got := ExpandValue(result.ProviderEnv["ANTHROPIC_CUSTOM_HEADERS"]); got != want {
+			t.Errorf("ANTHROPIC_CUSTOM_HEADERS = %q, want %q", got, want)
+		}
+		if _, ok := result.Settings["headers"]; ok {
+			t.Error("Settings should not contain the headers field")
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		cleanup := setupTestDir(t)
+		defer cleanup()
+
+		if _, err := PrepareIsolated(setupTestConfig(t), "unknown"); err == nil {
+			t.Error("PrepareIsolated() should fail for unknown provider")
+		}
+	})
+}
+
 func TestGetAuthToken(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -497,14 +714,46 @@ func TestValidateProvider(t *testing.T) {
 	})
 }
 
+func TestResolve(t *testing.T) {
+	cfg := setupTestConfig(t)
+	cfg.Providers["kimi"]["aliases"] = []interface{}{"k"}
+	cfg.Aliases = map[string]string{"zhipu": "glm", "gone": "deleted"}
+
+	for name, want := range map[string]string{"kimi": "kimi", "k": "kimi", "zhipu": "glm"} {
+		if got, err := Resolve(cfg, name); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"unknown", "gone"} {
+		if _, err := Resolve(cfg, name); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Resolve(%q) error = %v, want not found", name, err)
+		}
+	}
+	if _, err := Resolve(nil, "kimi"); err == nil {
+		t.Error("Resolve() should error for nil config")
+	}
+}
+
 func TestGetDefaultProvider(t *testing.T) {
 	t.Run("returns first provider", func(t *testing.T) {
 		cfg := setupTestConfig(t)
 
 		provider := GetDefaultProvider(cfg)
-		// Since map iteration order is random, just check it's one of the valid providers
-		if provider != "kimi" && provider != "glm" {
-			t.Errorf("GetDefaultProvider() = %s, want kimi or glm", provider)
+		if want := cfg.ProviderNames()[0]; provider != want {
+			t.Errorf("GetDefaultProvider() = %s, want %s", provider, want)
+		}
+	})
+
+	t.Run("returns default_provider", func(t *testing.T) {
+		cfg := setupTestConfig(t)
+		cfg.DefaultProvider = "glm"
+		if provider := GetDefaultProvider(cfg); provider != "glm" {
+			t.Errorf("GetDefaultProvider() = %s, want glm", provider)
+		}
+
+		cfg.DefaultProvider = "missing"
+		if provider := GetDefaultProvider(cfg); provider != cfg.ProviderNames()[0] {
+			t.Errorf("GetDefaultProvider() with a missing default = %s, want the first provider", provider)
 		}
 	})
 
@@ -683,3 +932,48 @@ func TestGetModel(t *testing.T) {
 // PreToolUse hook is not supported yet. Users should use claude_args
 // --disallowed-tools instead. The configuration is commented out in
 // provider.go with a note for future reference.
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("CCC_TEST_KIMI_TOKEN", "sk-$ecret")
+	t.Setenv("CCC_TEST_VAR_NAME", "CCC_TEST_KIMI_TOKEN")
+
+	envMap := map[string]interface{}{
+		"ANTHROPIC_BASE_URL":   "https://api.moonshot.cn/anthropic",
+		"ANTHROPIC_AUTH_TOKEN": "env:${CCC_TEST_VAR_NAME}",
+		"API_TIMEOUT":          "${CCC_TEST_TIMEOUT}",
+	}
+
+	resolved, err := ResolveSecrets("kimi", envMap)
+	if err != nil {
+		t.Fatalf("ResolveSecrets() error = %v", err)
+	}
+	if got := resolved["ANTHROPIC_AUTH_TOKEN"]; got != secret.Value("sk-$ecret") {
+		t.Errorf("AUTH_TOKEN = %#v, want resolved secret", got)
+	}
+	if got := resolved["API_TIMEOUT"]; got != "${CCC_TEST_TIMEOUT}" {
+		t.Errorf("API_TIMEOUT = %#v, want unchanged value", got)
+	}
+	if envMap["ANTHROPIC_AUTH_TOKEN"] != "env:${CCC_TEST_VAR_NAME}" {
+		t.Error("ResolveSecrets() should not modify its input")
+	}
+
+	// Resolved secrets must not be expanded a second time
+	pairs := EnvMapToPairs(resolved)
+	for _, pair := range pairs {
+		if pair.Key == "ANTHROPIC_AUTH_TOKEN" && pair.Value != "sk-$ecret" {
+			t.Errorf("EnvMapToPairs() token = %q, want sk-$ecret", pair.Value)
+		}
+	}
+
+	t.Run("error names provider and key", func(t *testing.T) {
+		_, err := ResolveSecrets("kimi", map[string]interface{}{
+			"ANTHROPIC_AUTH_TOKEN": "env:CCC_TEST_UNSET_TOKEN",
+		})
+		if err == nil {
+			t.Fatal("ResolveSecrets() should return error")
+		}
+		if !strings.Contains(err.Error(), "provider 'kimi'") || !strings.Contains(err.Error(), "ANTHROPIC_AUTH_TOKEN") {
+			t.Errorf("error = %v, want provider and key", err)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+// Package secret resolves secret references used in place of plaintext
+// values in ccc.json, e.g. "cmd:pass show kimi" or "file:~/.kimi-token".
+package secret
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Resolver resolves the part of a reference after "<scheme>:" to the secret value.
+// Errors must never include the secret itself.
+type Resolver func(ref string) (string, error)
+
+// Value is a resolved secret. It is a distinct type so that consumers can
+// tell resolved secrets apart from configured values, e.g. to skip ${VAR}
+// expansion which would corrupt secrets containing '$'.
+type Value string
+
+var (
+	mu        sync.RWMutex
+	resolvers = map[string]Resolver{
+		"env":  resolveEnv,
+		"file": resolveFile,
+		"cmd":  resolveCmd,
+	}
+)
+
+// Register adds or replaces the resolver for a scheme.
+func Register(scheme string, resolver Resolver) {
+	mu.Lock()
+	defer mu.Unlock()
+	resolvers[scheme] = resolver
+}
+
+// Scheme returns the registered scheme of a secret reference.
+// Returns false if value is not a reference to a registered scheme.
+func Scheme(value string) (string, bool) {
+	scheme, _, found := strings.Cut(value, ":")
+	if !found {
+		return "", false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := resolvers[scheme]
+	return scheme, ok
+}
+
+// IsRef reports whether value is a secret reference.
+func IsRef(value string) bool {
+	_, ok := Scheme(value)
+	return ok
+}
+
+// Resolve returns the secret for a reference.
+// Values that are not references are returned unchanged.
+func Resolve(value string) (string, error) {
+	scheme, ok := Scheme(value)
+	if !ok {
+		return value, nil
+	}
+	mu.RLock()
+	resolver := resolvers[scheme]
+	mu.RUnlock()
+
+	secret, err := resolver(strings.TrimPrefix(value, scheme+":"))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", scheme, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%s: resolved to an empty value", scheme)
+	}
+	return secret, nil
+}
+
+// resolveEnv reads the secret from an environment variable: env:NAME
+func resolveEnv(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFile reads the secret from a file, ignoring surrounding whitespace: file:/path
+func resolveFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(homeDir, path[2:])
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("file %s does not exist", path)
+		}
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveCmd runs a shell command and uses its trimmed stdout: cmd:pass show kimi
+// Stdin and stderr are inherited so that password managers can prompt.
+func resolveCmd(command string) (string, error) {
+	var stdout bytes.Buffer
+	c := exec.Command("sh", "-c", command)
+	c.Stdin = os.Stdin
+	c.Stdout = &stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("command failed: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,100 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsRef(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"env:KIMI_TOKEN", true},
+		{"file:/etc/kimi", true},
+		{"cmd:pass show kimi", true},
+		{"sk-plain-token", false},
+		{"https://api.moonshot.cn/anthropic", false},
+		{"vault:secret/kimi", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsRef(tt.value); got != tt.want {
+			t.Errorf("IsRef(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("CCC_TEST_SECRET", "sk-from-env")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("sk-from-file\n"), 0600); err != nil {
+		t.Fatalf("write token file: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"plain value", "sk-plain", "sk-plain"},
+		{"env", "env:CCC_TEST_SECRET", "sk-from-env"},
+		{"file", "file:" + tokenFile, "sk-from-file"},
+		{"cmd", "cmd:echo sk-from-cmd", "sk-from-cmd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.value)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"missing env", "env:CCC_TEST_SECRET_MISSING", "CCC_TEST_SECRET_MISSING is not set"},
+		{"missing file", "file:/nonexistent/ccc-token", "does not exist"},
+		{"failing cmd", "cmd:echo sk-leaked; exit 3", "exit status 3"},
+		{"empty cmd output", "cmd:true", "empty value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resolve(tt.value)
+			if err == nil {
+				t.Fatal("Resolve() should return error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), "sk-leaked") {
+				t.Errorf("error leaks command output: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-vault", func(ref string) (string, error) {
+		return "secret-for-" + ref, nil
+	})
+
+	got, err := Resolve("test-vault:kimi")
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got != "secret-for-kimi" {
+		t.Errorf("Resolve() = %q, want secret-for-kimi", got)
+	}
+}
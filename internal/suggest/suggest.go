@@ -0,0 +1,61 @@
+// Package suggest finds close matches for mistyped names, for "did you mean" hints.
+package suggest
+
+import "strings"
+
+// Distance returns the Levenshtein edit distance between a and b.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Closest returns the candidate that target most likely meant, or "" if
+// none is close enough. Matching is case-insensitive. A candidate that
+// equals target, or is the only one starting with it (or the only one
+// target starts with), wins; otherwise the candidate with the smallest
+// edit distance is returned if the distance is at most a third of the
+// length of target (at least 2), and ties are broken by candidate order.
+func Closest(target string, candidates []string) string {
+	if target == "" {
+		return ""
+	}
+	lower := strings.ToLower(target)
+
+	var prefixed []string
+	for _, c := range candidates {
+		lc := strings.ToLower(c)
+		if lc == lower {
+			return c
+		}
+		if strings.HasPrefix(lc, lower) || strings.HasPrefix(lower, lc) {
+			prefixed = append(prefixed, c)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0]
+	}
+
+	best, bestDistance := "", max(2, len([]rune(target))/3)+1
+	for _, c := range candidates {
+		if d := Distance(lower, strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
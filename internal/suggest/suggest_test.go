@@ -0,0 +1,45 @@
+package suggest
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"kimi", "kimi", 0},
+		{"kimi", "kim", 1},
+		{"kimi", "kmii", 2},
+		{"glm", "", 3},
+		{"kitten", "sitting", 3},
+		{"模型", "模形", 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"kimi", "kimi-intl", "glm", "deepseek", "claude-sonnet-4-5-20250929"}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"kimi", "kimi"},
+		{"KIMI", "kimi"},
+		{"kimii", "kimi"},       // kimi is the only candidate kimii starts with
+		{"kim", "kimi"},         // ambiguous prefix, falls back to edit distance
+		{"deepsek", "deepseek"}, // edit distance
+		{"claude-sonnet-4-5", "claude-sonnet-4-5-20250929"},
+		{"openai", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Closest(tt.target, candidates); got != tt.want {
+			t.Errorf("Closest(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Capabilities reports which Messages API features a provider handles
+// correctly, as found by the deep checks.
+type Capabilities struct {
+	Streaming bool `json:"streaming"` // Well-formed SSE stream
+	Tools     bool `json:"tools"`     // tool_use with valid JSON input, and a tool_result round trip
+	Thinking  bool `json:"thinking"`  // Extended thinking blocks
+	Images    bool `json:"images"`    // Image input
+}
+
+// Required reports whether the capabilities Claude Code cannot work without are present.
+func (c *Capabilities) Required() bool {
+	return c.Streaming && c.Tools
+}
+
+// String lists the capabilities, e.g. "streaming=yes tools=yes thinking=no images=yes".
+func (c *Capabilities) String() string {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	return fmt.Sprintf("streaming=%s tools=%s thinking=%s images=%s",
+		yesNo(c.Streaming), yesNo(c.Tools), yesNo(c.Thinking), yesNo(c.Images))
+}
+
+// contentBlock is a content block reassembled from a stream.
+type contentBlock struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Thinking string          `json:"thinking,omitempty"`
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Input    json.RawMessage `json:"input,omitempty"`
+
+	partialJSON strings.Builder
+}
+
+// streamedMessage is a message reassembled from a stream.
+type streamedMessage struct {
+	Content    []*contentBlock
+	StopReason string
+}
+
+// streamEventData is the union of the event payloads used to reassemble a message.
+type streamEventData struct {
+	Type         string        `json:"type"`
+	Index        int           `json:"index"`
+	ContentBlock *contentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		Thinking    string `json:"thinking"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+}
+
+// streamMessage sends a streamed /v1/messages request and reassembles the
+// response, checking the order of events, their JSON payloads and the
+// JSON input of tool_use blocks.
+func streamMessage(r *requester, body map[string]interface{}) (*streamedMessage, error) {
+	body["stream"] = true
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.do(func() (*http.Request, error) {
+		req, err := NewMessagesRequest(context.Background(), r.endpoint, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("accept", "text/event-stream")
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		if msg := strings.TrimSpace(string(buf)); msg != "" {
+			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
+		}
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		return nil, fmt.Errorf("unexpected Content-Type '%s', want text/event-stream", ct)
+	}
+
+	msg := &streamedMessage{}
+	started, stopped := false, false
+	err = ReadEvents(resp.Body, func(event Event) error {
+		if event.Type == "ping" {
+			return nil
+		}
+		var d streamEventData
+		if err := json.Unmarshal(event.Data, &d); err != nil {
+			return fmt.Errorf("invalid JSON in %s event: %v", event.Type, err)
+		}
+		if d.Type != event.Type {
+			return fmt.Errorf("event '%s' has data of type '%s'", event.Type, d.Type)
+		}
+		if !started && event.Type != "message_start" {
+			return fmt.Errorf("stream starts with '%s', want message_start", event.Type)
+		}
+		if stopped {
+			return fmt.Errorf("'%s' event after message_stop", event.Type)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if started {
+				return fmt.Errorf("duplicate message_start")
+			}
+			started = true
+		case "content_block_start":
+			if d.ContentBlock == nil || d.Index != len(msg.Content) {
+				return fmt.Errorf("content_block_start with unexpected index %d", d.Index)
+			}
+			msg.Content = append(msg.Content, d.ContentBlock)
+		case "content_block_delta":
+			if d.Index < 0 || d.Index >= len(msg.Content) {
+				return fmt.Errorf("content_block_delta for unknown block %d", d.Index)
+			}
+			block := msg.Content[d.Index]
+			switch d.Delta.Type {
+			case "text_delta":
+				block.Text += d.Delta.Text
+			case "thinking_delta":
+				block.Thinking += d.Delta.Thinking
+			case "input_json_delta":
+				block.partialJSON.WriteString(d.Delta.PartialJSON)
+			}
+		case "content_block_stop":
+			if d.Index < 0 || d.Index >= len(msg.Content) {
+				return fmt.Errorf("content_block_stop for unknown block %d", d.Index)
+			}
+			block := msg.Content[d.Index]
+			if block.Type == "tool_use" && block.partialJSON.Len() > 0 {
+				input := block.partialJSON.String()
+				if !json.Valid([]byte(input)) {
+					return fmt.Errorf("tool_use '%s' has invalid JSON input: %s", block.Name, input)
+				}
+				block.Input = json.RawMessage(input)
+			}
+		case "message_delta":
+			msg.StopReason = d.Delta.StopReason
+		case "message_stop":
+			stopped = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !stopped {
+		return nil, fmt.Errorf("stream ended without message_stop")
+	}
+	return msg, nil
+}
+
+// deepTool is the tool offered in the tool-use check.
+var deepTool = map[string]interface{}{
+	"name":        "add",
+	"description": "Add two integers and return the sum.",
+	"input_schema": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"a": map[string]string{"type": "integer"},
+			"b": map[string]string{"type": "integer"},
+		},
+		"required": []string{"a", "b"},
+	},
+}
+
+// deepImage is a 1x1 PNG for the image check.
+const deepImage = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8z8BQDwAEhQGAhKmMIQAAAABJRU5ErkJggg=="
+
+// userMessage returns a user message with the given content.
+func userMessage(content interface{}) map[string]interface{} {
+	return map[string]interface{}{"role": "user", "content": content}
+}
+
+// checkStreaming checks that a simple streamed request returns text.
+func checkStreaming(r *requester, model string) error {
+	msg, err := streamMessage(r, map[string]interface{}{
+		"model":      model,
+		"max_tokens": 32,
+		"messages":   []interface{}{userMessage("1+1=?")},
+	})
+	if err != nil {
+		return err
+	}
+	for _, block := range msg.Content {
+		if block.Type == "text" && block.Text != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("stream contained no text")
+}
+
+// checkTools asks for a tool call, then sends the tool result back.
+func checkTools(r *requester, model string) error {
+	question := userMessage("Use the add tool to compute 2+3.")
+	msg, err := streamMessage(r, map[string]interface{}{
+		"model":       model,
+		"max_tokens":  256,
+		"tools":       []interface{}{deepTool},
+		"tool_choice": map[string]string{"type": "any"},
+		"messages":    []interface{}{question},
+	})
+	if err != nil {
+		return err
+	}
+
+	var toolUse *contentBlock
+	for _, block := range msg.Content {
+		if block.Type == "tool_use" {
+			toolUse = block
+			break
+		}
+	}
+	if toolUse == nil {
+		return fmt.Errorf("no tool_use block in the response (stop_reason '%s')", msg.StopReason)
+	}
+	if toolUse.ID == "" || toolUse.Name != "add" {
+		return fmt.Errorf("tool_use block has id '%s' and name '%s', want an id and 'add'", toolUse.ID, toolUse.Name)
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(toolUse.Input, &input); err != nil {
+		return fmt.Errorf("tool_use input is not a JSON object: %s", toolUse.Input)
+	}
+
+	// Send back the text and tool_use blocks, as Claude Code does
+	var content []interface{}
+	for _, block := range msg.Content {
+		switch {
+		case block.Type == "text" && block.Text != "":
+			content = append(content, map[string]string{"type": "text", "text": block.Text})
+		case block == toolUse:
+			content = append(content, map[string]interface{}{
+				"type": "tool_use", "id": block.ID, "name": block.Name, "input": input,
+			})
+		}
+	}
+	assistant := map[string]interface{}{"role": "assistant", "content": content}
+	result := userMessage([]interface{}{map[string]interface{}{
+		"type":        "tool_result",
+		"tool_use_id": toolUse.ID,
+		"content":     "5",
+	}})
+	_, err = streamMessage(r, map[string]interface{}{
+		"model":      model,
+		"max_tokens": 64,
+		"tools":      []interface{}{deepTool},
+		"messages":   []interface{}{question, assistant, result},
+	})
+	if err != nil {
+		return fmt.Errorf("tool_result round trip: %w", err)
+	}
+	return nil
+}
+
+// checkThinking checks that extended thinking produces a thinking block.
+func checkThinking(r *requester, model string) error {
+	msg, err := streamMessage(r, map[string]interface{}{
+		"model":      model,
+		"max_tokens": 2048,
+		"thinking":   map[string]interface{}{"type": "enabled", "budget_tokens": 1024},
+		"messages":   []interface{}{userMessage("Is 91 a prime number?")},
+	})
+	if err != nil {
+		return err
+	}
+	for _, block := range msg.Content {
+		if block.Type == "thinking" || block.Type == "redacted_thinking" {
+			return nil
+		}
+	}
+	return fmt.Errorf("no thinking block in the response")
+}
+
+// checkImages checks that a request with an image is accepted.
+func checkImages(r *requester, model string) error {
+	_, err := streamMessage(r, map[string]interface{}{
+		"model":      model,
+		"max_tokens": 32,
+		"messages": []interface{}{userMessage([]interface{}{
+			map[string]interface{}{
+				"type":   "image",
+				"source": map[string]string{"type": "base64", "media_type": "image/png", "data": deepImage},
+			},
+			map[string]string{"type": "text", "text": "What color is this image?"},
+		})},
+	})
+	return err
+}
+
+// deepCheck runs the deep checks against a provider, recording the
+// capabilities in the result and a warning for every failed check.
+func deepCheck(result *ValidationResult, r *requester) {
+	if result.Model == "" {
+		result.Warnings = append(result.Warnings, "Deep checks skipped: ANTHROPIC_MODEL is not set")
+		return
+	}
+
+	caps := &Capabilities{}
+	checks := []struct {
+		name  string
+		check func(*requester, string) error
+		ok    *bool
+	}{
+		{"streaming", checkStreaming, &caps.Streaming},
+		{"tools", checkTools, &caps.Tools},
+		{"thinking", checkThinking, &caps.Thinking},
+		{"images", checkImages, &caps.Images},
+	}
+	for _, c := range checks {
+		if err := c.check(r, result.Model); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Deep check %s failed: %v", c.name, err))
+			continue
+		}
+		*c.ok = true
+	}
+	result.Capabilities = caps
+}
@@ -0,0 +1,150 @@
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// writeSSE writes events as a server-sent event stream.
+func writeSSE(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, data := range events {
+		var d struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(data), &d)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", d.Type, data)
+	}
+}
+
+// newDeepServer returns a fake provider for the deep checks. toolInput is
+// streamed as the tool_use input; images are rejected if rejectImages is set.
+func newDeepServer(t *testing.T, toolInput string, rejectImages bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true {
+			fmt.Fprint(w, `{"content":[{"type":"text","text":"2"}]}`)
+			return
+		}
+		body, _ := json.Marshal(req)
+		messages := req["messages"].([]interface{})
+
+		start := `{"type":"message_start","message":{"id":"msg_1"}}`
+		stop := `{"type":"message_stop"}`
+		switch {
+		case rejectImages && strings.Contains(string(body), `"image"`):
+			http.Error(w, `{"error":"images not supported"}`, http.StatusBadRequest)
+		case req["tools"] != nil && len(messages) == 1:
+			writeSSE(w, start,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"add","input":{}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":`+fmt.Sprintf("%q", toolInput)+`}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"tool_use"}}`, stop)
+		case req["thinking"] != nil:
+			writeSSE(w, start,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"91 = 7*13"}}`,
+				`{"type":"content_block_stop","index":0}`, stop)
+		default:
+			writeSSE(w, start,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"2"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"}}`, stop)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestValidateDeep(t *testing.T) {
+	tests := []struct {
+		name         string
+		toolInput    string
+		rejectImages bool
+		want         Capabilities
+		wantStatus   string
+		wantWarning  string
+	}{
+		{"all supported", `{"a": 2, "b": 3}`, false, Capabilities{true, true, true, true}, "valid", ""},
+		{"no images", `{"a": 2, "b": 3}`, true, Capabilities{true, true, true, false}, "valid", "Deep check images failed: HTTP 400"},
+		{"malformed tool input", `{"a": 2, "b": `, false, Capabilities{true, false, true, true}, "warning", "has invalid JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newDeepServer(t, tt.toolInput, tt.rejectImages)
+			cfg := newMockConfig(map[string]map[string]interface{}{
+				"p": {"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL":   server.URL,
+					"ANTHROPIC_AUTH_TOKEN": "test-token",
+					"ANTHROPIC_MODEL":      "m",
+				}},
+			}, "p")
+
+			result := validateProvider(cfg, "p", &RunOptions{Deep: true})
+			if result.Capabilities == nil {
+				t.Fatalf("Capabilities = nil, warnings = %q", result.Warnings)
+			}
+			if *result.Capabilities != tt.want {
+				t.Errorf("Capabilities = %+v, want %+v (warnings %q)", *result.Capabilities, tt.want, result.Warnings)
+			}
+			if result.Status() != tt.wantStatus {
+				t.Errorf("Status() = %q, want %q", result.Status(), tt.wantStatus)
+			}
+			warnings := strings.Join(result.Warnings, "\n")
+			if (tt.wantWarning == "") != (warnings == "") || !strings.Contains(warnings, tt.wantWarning) {
+				t.Errorf("Warnings = %q, want %q", result.Warnings, tt.wantWarning)
+			}
+		})
+	}
+
+	// Without --deep, capabilities are not checked
+	server := newDeepServer(t, `{}`, false)
+	cfg := newMockConfig(map[string]map[string]interface{}{
+		"p": {"env": map[string]interface{}{"ANTHROPIC_BASE_URL": server.URL, "ANTHROPIC_AUTH_TOKEN": "t", "ANTHROPIC_MODEL": "m"}},
+	}, "p")
+	if result := ValidateProvider(cfg, "p"); result.Capabilities != nil {
+		t.Errorf("Capabilities = %+v, want nil", result.Capabilities)
+	}
+}
+
+func TestStreamMessageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		want   string
+	}{
+		{"missing message_start", []string{`{"type":"message_stop"}`}, "want message_start"},
+		{"missing message_stop", []string{`{"type":"message_start"}`}, "without message_stop"},
+		{"unknown block", []string{`{"type":"message_start"}`, `{"type":"content_block_delta","index":3,"delta":{}}`}, "unknown block 3"},
+		{"event after stop", []string{`{"type":"message_start"}`, `{"type":"message_stop"}`, `{"type":"message_delta","delta":{}}`}, "after message_stop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeSSE(w, tt.events...)
+			}))
+			defer server.Close()
+			_, err := streamMessage(testRequester(t, server.URL, 0, 0), map[string]interface{}{"model": "m"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("streamMessage() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// A JSON response to a streamed request is reported
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+	if _, err := streamMessage(testRequester(t, server.URL, 0, 0), map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "Content-Type") {
+		t.Errorf("streamMessage() error = %v, want Content-Type error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package validate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// Endpoint is a provider API endpoint and how to reach it.
+type Endpoint struct {
+	BaseURL   string
+	AuthToken string            // ANTHROPIC_AUTH_TOKEN, sent in the AuthStyle
+	APIKey    string            // ANTHROPIC_API_KEY, sent as x-api-key
+	AuthStyle string            // config.AuthStyleBearer (default) or config.AuthStyleAPIKey
+	Headers   map[string]string // Custom headers, overriding the default ones
+	Network   *config.Network   // Proxy and TLS settings, nil for direct connections
+}
+
+// setHeaders sets the credentials, the auth token in the endpoint's auth
+// style and the API key as x-api-key, then the custom headers, which may
+// replace the headers already set on req.
+func (e Endpoint) setHeaders(req *http.Request) {
+	if e.AuthToken != "" {
+		if e.AuthStyle == config.AuthStyleAPIKey {
+			req.Header.Set("x-api-key", e.AuthToken)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+e.AuthToken)
+		}
+	}
+	if e.APIKey != "" {
+		req.Header.Set("x-api-key", e.APIKey)
+	}
+	for name, value := range e.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// NewHTTPClient returns an HTTP client for the endpoint with the given
+// timeout, honoring its proxy, CA file, client certificate and
+// insecure_skip_verify settings. Without a proxy setting, the proxy
+// environment variables apply as usual.
+func (e Endpoint) NewHTTPClient(timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if n := e.Network; n != nil {
+		if n.Proxy != "" {
+			proxyURL, err := url.Parse(n.Proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy '%s': %w", n.Proxy, err)
+			}
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+
+		tlsConfig := &tls.Config{InsecureSkipVerify: n.InsecureSkipVerify}
+		if n.CAFile != "" {
+			pem, err := os.ReadFile(n.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ca_file: %w", err)
+			}
+			// Extend the system pool, so public endpoints keep working
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("ca_file '%s' contains no PEM certificates", n.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if n.ClientCert != "" {
+			cert, err := tls.LoadX509KeyPair(n.ClientCert, n.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client_cert/client_key: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
@@ -0,0 +1,180 @@
+package validate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/guyskk/ccc/internal/config"
+)
+
+// modelsHandler answers /v1/models with a single model.
+var modelsHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `{"data":[{"id":"m1"}]}`)
+})
+
+// writePEM writes a PEM block of the given type to a file in dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEndpointCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(modelsHandler)
+	defer server.Close()
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", server.Certificate().Raw)
+
+	tests := []struct {
+		name    string
+		network *config.Network
+		wantErr bool
+	}{
+		{"untrusted certificate", nil, true},
+		{"ca_file", &config.Network{CAFile: caFile}, false},
+		{"insecure_skip_verify", &config.Network{InsecureSkipVerify: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FetchModels(Endpoint{BaseURL: server.URL, AuthToken: "t", Network: tt.network})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FetchModels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := FetchModels(Endpoint{BaseURL: server.URL, Network: &config.Network{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}); err == nil {
+		t.Error("expected an error for a missing ca_file")
+	}
+}
+
+func TestEndpointClientCert(t *testing.T) {
+	server := httptest.NewUnstartedServer(modelsHandler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ccc-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	network := &config.Network{
+		InsecureSkipVerify: true,
+		ClientCert:         writePEM(t, dir, "client.crt", "CERTIFICATE", certDER),
+		ClientKey:          writePEM(t, dir, "client.key", "EC PRIVATE KEY", keyDER),
+	}
+
+	if _, err := FetchModels(Endpoint{BaseURL: server.URL, Network: &config.Network{InsecureSkipVerify: true}}); err == nil {
+		t.Error("expected the server to reject a request without a client certificate")
+	}
+	if models, err := FetchModels(Endpoint{BaseURL: server.URL, Network: network}); err != nil || len(models) != 1 {
+		t.Errorf("FetchModels() with client certificate = %v, %v", models, err)
+	}
+}
+
+func TestEndpointProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		modelsHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	endpoint := Endpoint{BaseURL: "http://api.internal.example", Network: &config.Network{Proxy: proxy.URL}}
+	if _, err := FetchModels(endpoint); err != nil {
+		t.Fatalf("FetchModels() error = %v", err)
+	}
+	if proxied != "http://api.internal.example/v1/models" {
+		t.Errorf("proxy received %q, want the absolute /v1/models URL", proxied)
+	}
+}
+
+func TestEndpointHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		modelsHandler(w, r)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		want     map[string]string
+	}{
+		{
+			"bearer by default",
+			Endpoint{BaseURL: server.URL, AuthToken: "sk-1"},
+			map[string]string{"Authorization": "Bearer sk-1", "X-Api-Key": "", "User-Agent": "claude-cli/2.0.76 (external, cli)"},
+		},
+		{
+			"x-api-key with custom headers",
+			Endpoint{
+				BaseURL:   server.URL,
+				AuthToken: "sk-1",
+				AuthStyle: config.AuthStyleAPIKey,
+				Headers:   map[string]string{"X-Tenant": "team-a", "user-agent": "corp-gateway-client"},
+			},
+			map[string]string{"Authorization": "", "X-Api-Key": "sk-1", "X-Tenant": "team-a", "User-Agent": "corp-gateway-client"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(request string) {
+				for name, value := range tt.want {
+					if got.Get(name) != value {
+						t.Errorf("%s header %s = %q, want %q", request, name, got.Get(name), value)
+					}
+				}
+			}
+
+			if _, err := FetchModels(tt.endpoint); err != nil {
+				t.Fatalf("FetchModels() error = %v", err)
+			}
+			check("/v1/models")
+
+			req, err := NewMessagesRequest(context.Background(), tt.endpoint, []byte(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			check("/v1/messages")
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package validate
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/guyskk/ccc/internal/prettyjson"
+)
+
+// Output formats supported by Run.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{FormatText, FormatJSON, FormatJUnit}
+
+// isValidFormat reports whether format is a supported output format.
+func isValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ANSI color codes used by the text format.
+const (
+	colorGreen  = "\033[32m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorReset  = "\033[0m"
+)
+
+// ColorEnabled reports whether text output to f should use ANSI colors.
+// Colors are disabled when NO_COLOR is set or f is not a terminal.
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// colorize wraps text in the given color if color output is enabled.
+func colorize(enabled bool, color, text string) string {
+	if !enabled {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// MarshalJSON serializes a result with snake_case keys, its status,
+// the API latency in milliseconds and the API error as a string.
+func (r *ValidationResult) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Provider  string `json:"provider"`
+		Status    string `json:"status"`
+		Valid     bool   `json:"valid"`
+		BaseURL   string `json:"base_url,omitempty"`
+		Model     string `json:"model,omitempty"`
+		APIStatus string `json:"api_status,omitempty"`
+		APIError  string `json:"api_error,omitempty"`
+		LatencyMS *int64 `json:"latency_ms,omitempty"`
+
+		Capabilities *Capabilities `json:"capabilities,omitempty"`
+		Warnings     []string      `json:"warnings"`
+		Errors       []string      `json:"errors"`
+	}{
+		Provider:  r.Provider,
+		Status:    r.Status(),
+		Valid:     r.Valid,
+		BaseURL:   r.BaseURL,
+		Model:     r.Model,
+		APIStatus: r.APIStatus,
+
+		Capabilities: r.Capabilities,
+		Warnings:     r.Warnings,
+		Errors:       r.Errors,
+	}
+	if r.APIError != nil {
+		out.APIError = r.APIError.Error()
+	}
+	if r.APIStatus != "" {
+		ms := r.Latency.Milliseconds()
+		out.LatencyMS = &ms
+	}
+	if out.Warnings == nil {
+		out.Warnings = []string{}
+	}
+	if out.Errors == nil {
+		out.Errors = []string{}
+	}
+	return json.Marshal(out)
+}
+
+// WriteReport writes the summary to w in the given format.
+// The text format prints every result followed by the summary,
+// using colors only when w is a terminal.
+func WriteReport(w io.Writer, format string, summary *ValidationSummary) error {
+	switch format {
+	case "", FormatText:
+		color := false
+		if f, ok := w.(*os.File); ok {
+			color = ColorEnabled(f)
+		}
+		for _, result := range summary.Results {
+			writeResult(w, result, color)
+		}
+		writeSummary(w, summary, color)
+		return nil
+	case FormatJSON:
+		data, err := prettyjson.Marshal(summary)
+		if err != nil {
+			return fmt.Errorf("failed to marshal validation report: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	case FormatJUnit:
+		return writeJUnit(w, summary)
+	default:
+		return fmt.Errorf("unknown format '%s' (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// PrintResult prints a single validation result to stdout,
+// with color coding when stdout is a terminal.
+func PrintResult(result *ValidationResult) {
+	writeResult(os.Stdout, result, ColorEnabled(os.Stdout))
+}
+
+// PrintSummary prints the validation summary for all providers to stdout,
+// with color coding when stdout is a terminal.
+func PrintSummary(summary *ValidationSummary) {
+	writeSummary(os.Stdout, summary, ColorEnabled(os.Stdout))
+}
+
+// writeResult writes a single validation result in text format.
+func writeResult(w io.Writer, result *ValidationResult, color bool) {
+	var status, statusColor string
+	switch result.Status() {
+	case "invalid":
+		status, statusColor = "Invalid", colorRed
+	case "warning":
+		status, statusColor = "Warning", colorYellow
+	default:
+		status, statusColor = "Valid", colorGreen
+	}
+
+	fmt.Fprintf(w, "  %s: %s\n", colorize(color, statusColor, status), result.Provider)
+
+	if result.BaseURL != "" {
+		fmt.Fprintf(w, "    Base URL: %s\n", result.BaseURL)
+	}
+	if result.Model != "" {
+		fmt.Fprintf(w, "    Model: %s\n", result.Model)
+	}
+	if result.APIStatus != "" {
+		apiStatus, apiColor := formatAPIStatus(result.APIStatus)
+		latency := ""
+		if result.Latency > 0 {
+			latency = fmt.Sprintf(" (%s)", result.Latency.Round(time.Millisecond))
+		}
+		fmt.Fprintf(w, "    API connection: %s%s\n", colorize(color, apiColor, apiStatus), latency)
+	}
+	if result.Capabilities != nil {
+		fmt.Fprintf(w, "    Capabilities: %s\n", result.Capabilities)
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "    Warning: %s\n", warning)
+	}
+	for _, err := range result.Errors {
+		fmt.Fprintf(w, "    Error: %s\n", err)
+	}
+}
+
+// formatAPIStatus formats the API status for display, returning the display text and color.
+func formatAPIStatus(status string) (string, string) {
+	if status == "ok" {
+		return "OK", colorGreen
+	}
+	return status, colorYellow
+}
+
+// writeSummary writes the validation summary in text format.
+func writeSummary(w io.Writer, summary *ValidationSummary, color bool) {
+	fmt.Fprintln(w)
+	for _, err := range summary.Errors {
+		fmt.Fprintf(w, "%s: %s\n", colorize(color, colorRed, "Error"), err)
+	}
+	if summary.Invalid > 0 {
+		fmt.Fprintf(w, "%s providers invalid\n", colorize(color, colorRed, fmt.Sprintf("%d/%d", summary.Invalid, summary.Total)))
+	} else if summary.Warning > 0 {
+		fmt.Fprintln(w, colorize(color, colorYellow, fmt.Sprintf("All providers valid (%d with API warnings)", summary.Warning)))
+	} else {
+		fmt.Fprintln(w, colorize(color, colorGreen, "All providers valid"))
+	}
+}
+
+// JUnit XML report structure, as consumed by CI test reporters.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSeconds formats a duration in seconds as used by JUnit reports.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// writeJUnit writes the summary as a JUnit XML report with one test case per provider,
+// and one for the configuration problems that belong to no provider, if any.
+// Invalid providers and failed API tests are reported as failures.
+func writeJUnit(w io.Writer, summary *ValidationSummary) error {
+	suite := junitTestSuite{Name: "ccc validate", Tests: len(summary.Results)}
+	var total time.Duration
+	for _, result := range summary.Results {
+		total += result.Latency
+		tc := junitTestCase{
+			ClassName: "ccc.validate",
+			Name:      result.Provider,
+			Time:      junitSeconds(result.Latency),
+		}
+
+		var out []string
+		if result.BaseURL != "" {
+			out = append(out, "Base URL: "+result.BaseURL)
+		}
+		if result.Model != "" {
+			out = append(out, "Model: "+result.Model)
+		}
+		if result.APIStatus != "" {
+			out = append(out, "API connection: "+result.APIStatus)
+		}
+		if result.Capabilities != nil {
+			out = append(out, "Capabilities: "+result.Capabilities.String())
+		}
+		for _, warning := range result.Warnings {
+			out = append(out, "Warning: "+warning)
+		}
+		tc.SystemOut = strings.Join(out, "\n")
+
+		switch result.Status() {
+		case "invalid":
+			tc.Failure = &junitFailure{
+				Message: strings.Join(result.Errors, "; "),
+				Type:    "invalid",
+				Text:    strings.Join(result.Errors, "\n"),
+			}
+		case "warning":
+			if result.APIStatus != "" && !isAPIStatusOK(result.APIStatus) {
+				tc.Failure = &junitFailure{
+					Message: "API test failed: " + result.APIStatus,
+					Type:    "api",
+					Text:    result.APIStatus,
+				}
+			} else {
+				tc.Failure = &junitFailure{
+					Message: "Deep checks failed: " + result.Capabilities.String(),
+					Type:    "deep",
+					Text:    strings.Join(result.Warnings, "\n"),
+				}
+			}
+		}
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if len(summary.Errors) > 0 {
+		suite.Tests++
+		suite.Failures++
+		suite.Cases = append(suite.Cases, junitTestCase{
+			ClassName: "ccc.validate",
+			Name:      "config",
+			Time:      junitSeconds(0),
+			Failure: &junitFailure{
+				Message: strings.Join(summary.Errors, "; "),
+				Type:    "invalid",
+				Text:    strings.Join(summary.Errors, "\n"),
+			},
+		})
+	}
+	suite.Time = junitSeconds(total)
+
+	report := junitTestSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
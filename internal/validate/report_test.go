@@ -0,0 +1,202 @@
+package validate
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSummary returns a summary with a valid, an invalid and a warning result.
+func testSummary() *ValidationSummary {
+	return summarize([]*ValidationResult{
+		{
+			Provider:  "kimi",
+			Valid:     true,
+			BaseURL:   "https://api.moonshot.cn/anthropic",
+			Model:     "kimi-k2-thinking",
+			APIStatus: "ok",
+			Latency:   1234 * time.Millisecond,
+		},
+		{
+			Provider: "broken",
+			Valid:    false,
+			Errors:   []string{"Missing required environment variable: ANTHROPIC_AUTH_TOKEN"},
+		},
+		{
+			Provider:  "glm",
+			Valid:     true,
+			BaseURL:   "https://open.bigmodel.cn/api/anthropic",
+			APIStatus: "HTTP 401: Unauthorized",
+			APIError:  errors.New("unauthorized"),
+			Latency:   50 * time.Millisecond,
+		},
+	})
+}
+
+func TestSummarize(t *testing.T) {
+	summary := testSummary()
+	if summary.Total != 3 || summary.Valid != 2 || summary.Invalid != 1 || summary.Warning != 1 {
+		t.Errorf("summarize() = %+v", summary)
+	}
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatJSON, testSummary()); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+
+	var report struct {
+		Total   int `json:"total"`
+		Invalid int `json:"invalid"`
+		Results []struct {
+			Provider  string   `json:"provider"`
+			Status    string   `json:"status"`
+			APIStatus string   `json:"api_status"`
+			APIError  string   `json:"api_error"`
+			LatencyMS *int64   `json:"latency_ms"`
+			Errors    []string `json:"errors"`
+			Warnings  []string `json:"warnings"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if report.Total != 3 || report.Invalid != 1 || len(report.Results) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	kimi, broken, glm := report.Results[0], report.Results[1], report.Results[2]
+	if kimi.Status != "valid" || kimi.LatencyMS == nil || *kimi.LatencyMS != 1234 {
+		t.Errorf("kimi = %+v, want valid with latency 1234", kimi)
+	}
+	if broken.Status != "invalid" || broken.LatencyMS != nil || len(broken.Errors) != 1 {
+		t.Errorf("broken = %+v, want invalid without latency", broken)
+	}
+	if glm.Status != "warning" || glm.APIError != "unauthorized" {
+		t.Errorf("glm = %+v, want warning with api_error", glm)
+	}
+	if kimi.Warnings == nil {
+		t.Error("warnings should be an empty list, not null")
+	}
+}
+
+func TestWriteReportJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatJUnit, testSummary()); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<?xml") {
+		t.Errorf("output should start with the XML header: %s", buf.String())
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	if report.Tests != 3 || report.Failures != 2 || len(report.Suites) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	cases := report.Suites[0].Cases
+	if cases[0].Failure != nil || cases[0].Time != "1.234" {
+		t.Errorf("kimi test case = %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Type != "invalid" {
+		t.Errorf("broken test case = %+v, want invalid failure", cases[1])
+	}
+	if cases[2].Failure == nil || cases[2].Failure.Type != "api" {
+		t.Errorf("glm test case = %+v, want api failure", cases[2])
+	}
+}
+
+func TestWriteReportText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatText, testSummary()); err != nil {
+		t.Fatalf("WriteReport() error = %v", err)
+	}
+	got := buf.String()
+	if strings.Contains(got, "\033[") {
+		t.Errorf("text written to a non-terminal should not contain colors: %q", got)
+	}
+	for _, want := range []string{"Valid: kimi", "Invalid: broken", "Warning: glm", "API connection: OK (1.234s)", "1/3 providers invalid"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestWriteReportConfigErrors(t *testing.T) {
+	summary := testSummary()
+	summary.Errors = []string{"alias 'gone' refers to missing provider 'deleted'"}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatText, summary); err != nil {
+		t.Fatalf("WriteReport(text) error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "Error: alias 'gone' refers to missing provider 'deleted'") {
+		t.Errorf("text output missing the error:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteReport(&buf, FormatJSON, summary); err != nil {
+		t.Fatalf("WriteReport(json) error = %v", err)
+	}
+	var report struct {
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil || len(report.Errors) != 1 {
+		t.Errorf("json errors = %q, %v", report.Errors, err)
+	}
+
+	buf.Reset()
+	if err := WriteReport(&buf, FormatJUnit, summary); err != nil {
+		t.Fatalf("WriteReport(junit) error = %v", err)
+	}
+	var junit junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &junit); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	cases := junit.Suites[0].Cases
+	if junit.Tests != 4 || junit.Failures != 3 || cases[3].Name != "config" || cases[3].Failure == nil {
+		t.Errorf("junit = %+v, want a failed config test case", junit)
+	}
+}
+
+func TestWriteReportUnknownFormat(t *testing.T) {
+	if err := WriteReport(&bytes.Buffer{}, "xml", testSummary()); err == nil {
+		t.Error("WriteReport() should fail for unknown format")
+	}
+	if err := Run(&mockConfig{}, &RunOptions{ValidateAll: true, Format: "xml"}); err == nil {
+		t.Error("Run() should fail for unknown format")
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if ColorEnabled(f) {
+		t.Error("ColorEnabled() should be false for a regular file")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(os.Stdout) {
+		t.Error("ColorEnabled() should be false when NO_COLOR is set")
+	}
+}
+
+func TestColorize(t *testing.T) {
+	if got := colorize(false, colorRed, "x"); got != "x" {
+		t.Errorf("colorize(false) = %q, want x", got)
+	}
+	if got := colorize(true, colorRed, "x"); got != colorRed+"x"+colorReset {
+		t.Errorf("colorize(true) = %q", got)
+	}
+}
@@ -0,0 +1,132 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout is the timeout of each validation request,
+// and defaultDeepTimeout the timeout of each deep check request.
+const (
+	defaultTimeout     = 8 * time.Second
+	defaultDeepTimeout = 60 * time.Second
+)
+
+// maxBackoff caps the delay between retries.
+const maxBackoff = 8 * time.Second
+
+// initialBackoff is the delay before the first retry, doubled for every further retry.
+// This variable allows tests to override the default behavior.
+var initialBackoff = 500 * time.Millisecond
+
+// requester sends validation requests to an endpoint with a timeout, retrying
+// network errors, HTTP 429 and HTTP 5xx with exponential backoff.
+type requester struct {
+	endpoint Endpoint
+	client   *http.Client
+	retries  int
+}
+
+// newRequester creates a requester for an endpoint. A zero timeout means defaultTimeout.
+// It fails if the endpoint's CA file or client certificate cannot be loaded.
+func newRequester(endpoint Endpoint, timeout time.Duration, retries int) (*requester, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	client, err := endpoint.NewHTTPClient(timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &requester{
+		endpoint: endpoint,
+		client:   client,
+		retries:  max(retries, 0),
+	}, nil
+}
+
+// isRetryable reports whether a response status is worth retrying.
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// do sends the request built by newRequest, retrying up to r.retries times.
+// The request is rebuilt for every attempt, so that its body can be resent.
+// The response of the last attempt is returned.
+func (r *requester) do(newRequest func() (*http.Request, error)) (*http.Response, error) {
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := r.client.Do(req)
+		if attempt >= r.retries || errors.Is(err, context.Canceled) {
+			return resp, err
+		}
+		if err == nil {
+			if !isRetryable(resp.StatusCode) {
+				return resp, nil
+			}
+			resp.Body.Close()
+		}
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// providerRequestOptions returns the timeout and retries for a provider:
+// the "validate" settings of the provider in ccc.json, e.g.
+// {"validate": {"timeout": "30s", "retries": 2}}, override the run options
+// unless these were given explicitly (see RunOptions.TimeoutSet).
+// A zero timeout means the default.
+func providerRequestOptions(provider map[string]interface{}, opts *RunOptions) (time.Duration, int, error) {
+	timeout, retries := opts.Timeout, opts.Retries
+
+	raw, ok := provider["validate"]
+	if !ok {
+		return timeout, retries, nil
+	}
+	settings, ok := raw.(map[string]interface{})
+	if !ok {
+		return 0, 0, fmt.Errorf("validate must be an object")
+	}
+	for key, value := range settings {
+		switch key {
+		case "timeout":
+			switch v := value.(type) {
+			case string:
+				d, err := time.ParseDuration(v)
+				if err != nil || d <= 0 {
+					return 0, 0, fmt.Errorf("validate.timeout must be a positive duration such as \"30s\", got %q", v)
+				}
+				if !opts.TimeoutSet {
+					timeout = d
+				}
+			case float64:
+				if v <= 0 {
+					return 0, 0, fmt.Errorf("validate.timeout must be positive, got %v", v)
+				}
+				if !opts.TimeoutSet {
+					timeout = time.Duration(v * float64(time.Second))
+				}
+			default:
+				return 0, 0, fmt.Errorf("validate.timeout must be a duration such as \"30s\" or a number of seconds")
+			}
+		case "retries":
+			v, ok := value.(float64)
+			if !ok || v < 0 || v != float64(int(v)) {
+				return 0, 0, fmt.Errorf("validate.retries must be a non-negative integer")
+			}
+			if !opts.RetriesSet {
+				retries = int(v)
+			}
+		default:
+			return 0, 0, fmt.Errorf("unknown validate setting '%s' (supported: timeout, retries)", key)
+		}
+	}
+	return timeout, retries, nil
+}
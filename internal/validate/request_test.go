@@ -0,0 +1,200 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRequester creates a requester for baseURL with token "t".
+func testRequester(t *testing.T, baseURL string, timeout time.Duration, retries int) *requester {
+	t.Helper()
+	r, err := newRequester(Endpoint{BaseURL: baseURL, AuthToken: "t"}, timeout, retries)
+	if err != nil {
+		t.Fatalf("newRequester() error = %v", err)
+	}
+	return r
+}
+
+func TestRequesterRetries(t *testing.T) {
+	original := initialBackoff
+	initialBackoff = time.Millisecond
+	defer func() { initialBackoff = original }()
+
+	tests := []struct {
+		name      string
+		failures  int32 // Requests answered with status before succeeding
+		status    int
+		retries   int
+		wantCalls int32
+		wantOK    bool
+	}{
+		{"no retries", 1, http.StatusServiceUnavailable, 0, 1, false},
+		{"recovers after retries", 2, http.StatusServiceUnavailable, 2, 3, true},
+		{"rate limited", 1, http.StatusTooManyRequests, 1, 2, true},
+		{"gives up", 5, http.StatusBadGateway, 2, 3, false},
+		{"client errors are not retried", 5, http.StatusUnauthorized, 3, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					return
+				}
+				fmt.Fprint(w, `{"data":[{"id":"m"}]}`)
+			}))
+			defer server.Close()
+
+			_, err := testRequester(t, server.URL, time.Second, tt.retries).fetchModels()
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err == nil) != tt.wantOK {
+				t.Errorf("err = %v, want ok = %v", err, tt.wantOK)
+			}
+		})
+	}
+
+	// Request bodies are resent on retries
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+	if status := testAPIConnection(testRequester(t, server.URL, time.Second, 1), "m"); status != "ok" {
+		t.Errorf("testAPIConnection() = %q, want ok", status)
+	}
+	if len(bodies) != 2 || bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Errorf("bodies = %q, want the same body twice", bodies)
+	}
+}
+
+func TestRequesterTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	if _, err := testRequester(t, server.URL, 20*time.Millisecond, 0).fetchModels(); err == nil {
+		t.Error("expected a timeout error")
+	}
+	if r := testRequester(t, server.URL, 0, -1); r.client.Timeout != defaultTimeout || r.retries != 0 {
+		t.Errorf("newRequester(0, -1) = timeout %v, retries %d", r.client.Timeout, r.retries)
+	}
+}
+
+func TestProviderRequestOptions(t *testing.T) {
+	opts := &RunOptions{Timeout: 5 * time.Second, Retries: 1}
+	tests := []struct {
+		name        string
+		validate    interface{}
+		wantTimeout time.Duration
+		wantRetries int
+		wantErr     string
+	}{
+		{"no overrides", nil, 5 * time.Second, 1, ""},
+		{"duration string", map[string]interface{}{"timeout": "30s"}, 30 * time.Second, 1, ""},
+		{"seconds", map[string]interface{}{"timeout": 1.5, "retries": 3.0}, 1500 * time.Millisecond, 3, ""},
+		{"bad duration", map[string]interface{}{"timeout": "soon"}, 0, 0, "positive duration"},
+		{"negative retries", map[string]interface{}{"retries": -1.0}, 0, 0, "non-negative integer"},
+		{"unknown key", map[string]interface{}{"concurrency": 2.0}, 0, 0, "unknown validate setting"},
+		{"not an object", "fast", 0, 0, "must be an object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := map[string]interface{}{}
+			if tt.validate != nil {
+				provider["validate"] = tt.validate
+			}
+			timeout, retries, err := providerRequestOptions(provider, opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || timeout != tt.wantTimeout || retries != tt.wantRetries {
+				t.Errorf("got %v, %d, %v; want %v, %d", timeout, retries, err, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestProviderRequestOptionsPrecedence(t *testing.T) {
+	provider := map[string]interface{}{"validate": map[string]interface{}{"timeout": "30s", "retries": 3.0}}
+	tests := []struct {
+		name        string
+		opts        *RunOptions
+		wantTimeout time.Duration
+		wantRetries int
+	}{
+		{"flags not set", &RunOptions{}, 30 * time.Second, 3},
+		{"explicit flags", &RunOptions{Timeout: 5 * time.Second, Retries: 1, TimeoutSet: true, RetriesSet: true}, 5 * time.Second, 1},
+		{"explicit zero retries", &RunOptions{RetriesSet: true}, 30 * time.Second, 0},
+		{"explicit timeout only", &RunOptions{Timeout: 5 * time.Second, TimeoutSet: true}, 5 * time.Second, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeout, retries, err := providerRequestOptions(provider, tt.opts)
+			if err != nil || timeout != tt.wantTimeout || retries != tt.wantRetries {
+				t.Errorf("got %v, %d, %v; want %v, %d", timeout, retries, err, tt.wantTimeout, tt.wantRetries)
+			}
+		})
+	}
+
+	// The provider settings are still checked
+	bad := map[string]interface{}{"validate": map[string]interface{}{"retries": -1.0}}
+	if _, _, err := providerRequestOptions(bad, &RunOptions{RetriesSet: true}); err == nil {
+		t.Error("invalid validate settings should be reported even when overridden")
+	}
+}
+
+func TestValidateAllProvidersOrderAndConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer server.Close()
+
+	providers := map[string]map[string]interface{}{}
+	for _, name := range []string{"zeta", "alpha", "mid", "beta", "omega", "gamma"} {
+		providers[name] = map[string]interface{}{"env": map[string]interface{}{
+			"ANTHROPIC_BASE_URL":   server.URL,
+			"ANTHROPIC_AUTH_TOKEN": "t",
+		}}
+	}
+
+	summary := validateAllProviders(newMockConfig(providers, ""), &RunOptions{Concurrency: 2})
+	var names []string
+	for _, r := range summary.Results {
+		names = append(names, r.Provider)
+	}
+	if got := strings.Join(names, ","); got != "alpha,beta,gamma,mid,omega,zeta" {
+		t.Errorf("order = %s, want sorted by name", got)
+	}
+	if maxInFlight > 2 {
+		t.Errorf("max providers in flight = %d, want <= 2", maxInFlight)
+	}
+}
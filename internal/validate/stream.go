@@ -0,0 +1,65 @@
+package validate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxEventSize limits the size of a single line of a server-sent event stream.
+const maxEventSize = 1024 * 1024
+
+// Event is a server-sent event of a streamed /v1/messages response.
+type Event struct {
+	Type string          // The event field, e.g. "content_block_delta"
+	Data json.RawMessage // The data field, JSON for the Messages API
+}
+
+// ReadEvents reads server-sent events from r and calls fn for each of them,
+// until the stream ends or fn returns an error. An "error" event is
+// returned as an error.
+func ReadEvents(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxEventSize)
+
+	var event Event
+	var data bytes.Buffer
+	dispatch := func() error {
+		if event.Type == "" && data.Len() == 0 {
+			return nil
+		}
+		event.Data = json.RawMessage(bytes.Clone(data.Bytes()))
+		defer func() { event, data = Event{}, bytes.Buffer{} }()
+		if event.Type == "error" {
+			return fmt.Errorf("stream error: %s", strings.TrimSpace(string(event.Data)))
+		}
+		return fn(event)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case field == "event":
+			event.Type = value
+		case field == "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+		// Comments (":...") and unknown fields are ignored
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
+}
@@ -0,0 +1,36 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadEvents(t *testing.T) {
+	stream := ": ping\n" +
+		"event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+		"event: content_block_delta\ndata: {\"a\":\ndata: 1}\n\n" +
+		"data: {\"no\":\"event\"}\n\n" +
+		"event: message_stop\ndata: {}"
+
+	var types, data []string
+	err := ReadEvents(strings.NewReader(stream), func(e Event) error {
+		types = append(types, e.Type)
+		data = append(data, string(e.Data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadEvents() error = %v", err)
+	}
+	if got := strings.Join(types, ","); got != "message_start,content_block_delta,,message_stop" {
+		t.Errorf("types = %q", got)
+	}
+	if data[1] != "{\"a\":\n1}" || data[3] != "{}" {
+		t.Errorf("data = %q", data)
+	}
+
+	stream = "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\"}}\n\n"
+	err = ReadEvents(strings.NewReader(stream), func(e Event) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("error event: err = %v", err)
+	}
+}
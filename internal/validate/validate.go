@@ -2,15 +2,23 @@
 package validate
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/guyskk/ccc/internal/config"
+	"github.com/guyskk/ccc/internal/suggest"
 )
 
 // ValidationResult represents the result of validating a provider configuration.
@@ -21,17 +29,40 @@ type ValidationResult struct {
 	Errors    []string
 	BaseURL   string
 	Model     string
-	APIStatus string // "ok", "failed", "skipped"
-	APIError  error
+	APIStatus string        // "ok", "failed", "skipped"
+	APIError  error         // Why the API test failed, nil if it passed or did not run
+	Latency   time.Duration // Duration of the API test, zero if not run
+
+	// Capabilities found by the deep checks, nil if they did not run
+	Capabilities *Capabilities
+}
+
+// Status returns "valid", "invalid" or "warning" (valid, but the API test
+// failed or the deep checks found streaming or tool use broken).
+func (r *ValidationResult) Status() string {
+	if !r.Valid {
+		return "invalid"
+	}
+	if r.APIStatus != "" && !isAPIStatusOK(r.APIStatus) {
+		return "warning"
+	}
+	if r.Capabilities != nil && !r.Capabilities.Required() {
+		return "warning"
+	}
+	return "valid"
 }
 
 // ValidationSummary represents the summary of validating multiple providers.
 type ValidationSummary struct {
-	Total   int
-	Valid   int
-	Invalid int
-	Warning int
-	Results []*ValidationResult
+	Total   int                 `json:"total"`
+	Valid   int                 `json:"valid"`
+	Invalid int                 `json:"invalid"`
+	Warning int                 `json:"warning"`
+	Results []*ValidationResult `json:"results"`
+
+	// Errors are configuration problems that belong to no provider,
+	// e.g. top-level aliases of missing providers
+	Errors []string `json:"errors,omitempty"`
 }
 
 // Provider represents a provider configuration for validation.
@@ -46,8 +77,25 @@ type Provider interface {
 type Config interface {
 	// Providers returns all providers.
 	Providers() map[string]map[string]interface{}
+	// ProviderNames returns the provider names in declaration order.
+	ProviderNames() []string
 	// CurrentProvider returns the current provider name.
 	CurrentProvider() string
+	// ResolveProvider returns the effective provider configuration,
+	// e.g. with inheritance applied. It returns an error if the provider
+	// configuration cannot be resolved.
+	ResolveProvider(name string) (map[string]interface{}, error)
+	// IsFailoverGroup reports whether the provider is a failover group,
+	// e.g. with inheritance applied. Unlike ResolveProvider it must not
+	// resolve secrets, as it is used for providers that are not validated.
+	IsFailoverGroup(name string) bool
+	// ResolveAlias returns the provider name that name is an alias of,
+	// or name itself if it is not an alias.
+	ResolveAlias(name string) string
+	// CheckAliases returns the problems with the name and aliases of a
+	// provider, e.g. names that collide with provider names or subcommands.
+	// An empty name checks the aliases that belong to no provider.
+	CheckAliases(name string) []string
 }
 
 // Model represents a model from the /v1/models API response.
@@ -60,26 +108,32 @@ type ModelsResponse struct {
 	Data []Model `json:"data"`
 }
 
-// fetchAvailableModels fetches the list of available models from the provider.
-func fetchAvailableModels(baseURL, authToken string) ([]string, error) {
-	client := &http.Client{
-		Timeout: 8 * time.Second,
-	}
-
-	modelsURL := strings.TrimSuffix(baseURL, "/") + "/v1/models"
-
-	req, err := http.NewRequest("GET", modelsURL, nil)
+// FetchModels fetches the list of available models from the endpoint.
+func FetchModels(endpoint Endpoint) ([]string, error) {
+	r, err := newRequester(endpoint, 0, 0)
 	if err != nil {
 		return nil, err
 	}
+	return r.fetchModels()
+}
 
-	// Use standard headers compatible with third-party providers
-	req.Header.Set("Authorization", "Bearer "+authToken)
-	req.Header.Set("accept", "application/json")
-	req.Header.Set("user-agent", "claude-cli/2.0.76 (external, cli)")
-	req.Header.Set("x-app", "cli")
+// fetchModels fetches the list of available models from the endpoint.
+func (r *requester) fetchModels() ([]string, error) {
+	modelsURL := strings.TrimSuffix(r.endpoint.BaseURL, "/") + "/v1/models"
 
-	resp, err := client.Do(req)
+	resp, err := r.do(func() (*http.Request, error) {
+		req, err := http.NewRequest("GET", modelsURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Use standard headers compatible with third-party providers
+		req.Header.Set("accept", "application/json")
+		req.Header.Set("user-agent", "claude-cli/2.0.76 (external, cli)")
+		req.Header.Set("x-app", "cli")
+		r.endpoint.setHeaders(req)
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -101,9 +155,9 @@ func fetchAvailableModels(baseURL, authToken string) ([]string, error) {
 	return models, nil
 }
 
-// selectBestModel selects the best model from a list based on priority (sonnet > haiku > opus)
+// SelectBestModel selects the best model from a list based on priority (sonnet > haiku > opus)
 // and recency (latest date within each priority group).
-func selectBestModel(models []string) string {
+func SelectBestModel(models []string) string {
 	if len(models) == 0 {
 		return ""
 	}
@@ -161,6 +215,12 @@ func selectBestModel(models []string) string {
 
 // ValidateProvider validates a single provider configuration.
 func ValidateProvider(cfg Config, providerName string) *ValidationResult {
+	return validateProvider(cfg, providerName, &RunOptions{})
+}
+
+// validateProvider validates a single provider configuration,
+// running the deep checks if requested and the API test passed.
+func validateProvider(cfg Config, providerName string, opts *RunOptions) *ValidationResult {
 	result := &ValidationResult{
 		Provider:  providerName,
 		Valid:     true,
@@ -172,13 +232,52 @@ func ValidateProvider(cfg Config, providerName string) *ValidationResult {
 	providers := cfg.Providers()
 
 	// Check if provider exists
-	provider, exists := providers[providerName]
-	if !exists {
+	if _, exists := providers[providerName]; !exists {
 		result.Valid = false
 		result.Errors = append(result.Errors, fmt.Sprintf("Provider '%s' not found in configuration", providerName))
 		return result
 	}
 
+	if problems := cfg.CheckAliases(providerName); len(problems) > 0 {
+		result.Valid = false
+		result.Errors = append(result.Errors, problems...)
+	}
+
+	// Resolve the effective configuration (e.g. extends chain)
+	provider, err := cfg.ResolveProvider(providerName)
+	if err != nil {
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("Invalid provider configuration: %v", err))
+		return result
+	}
+
+	// Failover groups have no endpoint of their own, validate the members list instead
+	if members, isGroup := provider["failover"]; isGroup {
+		validateFailoverGroup(cfg, result, members)
+		return result
+	}
+
+	timeout, retries, err := providerRequestOptions(provider, opts)
+	if err != nil {
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("Invalid provider configuration: %v", err))
+		return result
+	}
+	var headers map[string]string
+	var authStyle string
+	network, err := config.GetNetwork(provider)
+	if err == nil {
+		headers, err = config.GetHeaders(provider)
+	}
+	if err == nil {
+		authStyle, err = config.GetAuthStyle(provider)
+	}
+	if err != nil {
+		result.Valid = false
+		result.Errors = append(result.Errors, fmt.Sprintf("Invalid provider configuration: %v", err))
+		return result
+	}
+
 	// Extract env from provider config
 	var env map[string]interface{}
 	if envVal, ok := provider["env"]; ok {
@@ -193,7 +292,6 @@ func ValidateProvider(cfg Config, providerName string) *ValidationResult {
 
 	// Check required environment variables
 	baseURL, hasBaseURL := env["ANTHROPIC_BASE_URL"].(string)
-	authToken, hasAuthToken := env["ANTHROPIC_AUTH_TOKEN"].(string)
 
 	if !hasBaseURL || baseURL == "" {
 		result.Valid = false
@@ -214,9 +312,17 @@ func ValidateProvider(cfg Config, providerName string) *ValidationResult {
 		}
 	}
 
-	if !hasAuthToken || authToken == "" {
+	// Claude Code sends ANTHROPIC_AUTH_TOKEN and ANTHROPIC_API_KEY if set,
+	// and the output of apiKeyHelper otherwise
+	authToken, apiKey, err := config.GetCredentials(provider)
+	if err != nil {
 		result.Valid = false
-		result.Errors = append(result.Errors, "Missing required environment variable: ANTHROPIC_AUTH_TOKEN")
+		result.Errors = append(result.Errors, err.Error())
+	} else if authToken == "" && apiKey == "" {
+		result.Valid = false
+		result.Errors = append(result.Errors, "Missing required environment variable: ANTHROPIC_AUTH_TOKEN or ANTHROPIC_API_KEY (or an apiKeyHelper)")
+	} else if token, _ := env["ANTHROPIC_AUTH_TOKEN"].(string); token != "" && apiKey != "" {
+		result.Warnings = append(result.Warnings, "Both ANTHROPIC_AUTH_TOKEN and ANTHROPIC_API_KEY are set, Claude Code sends both (Authorization and x-api-key)")
 	}
 
 	// Check model if present
@@ -227,44 +333,145 @@ func ValidateProvider(cfg Config, providerName string) *ValidationResult {
 	}
 
 	// Test API connection if config is valid so far
-	if result.Valid && hasBaseURL && hasAuthToken {
-		result.APIStatus = testAPIConnection(baseURL, authToken, model)
+	if result.Valid {
+		endpoint := Endpoint{
+			BaseURL:   baseURL,
+			AuthToken: authToken,
+			APIKey:    apiKey,
+			AuthStyle: authStyle,
+			Headers:   headers,
+			Network:   network,
+		}
+		r, err := newRequester(endpoint, timeout, retries)
+		if err != nil {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("Invalid provider configuration: %v", err))
+			return result
+		}
+		start := time.Now()
+		result.APIStatus = testAPIConnection(r, model)
+		result.Latency = time.Since(start)
+		if !isAPIStatusOK(result.APIStatus) {
+			result.APIError = errors.New(result.APIStatus)
+		}
+
+		// Cross-check the configured models, if the provider lists its models
+		if configured := configuredModels(env); len(configured) > 0 {
+			if models, err := r.fetchModels(); err == nil && len(models) > 0 {
+				result.Warnings = append(result.Warnings, checkModels(configured, env, models)...)
+			}
+		}
+
+		if opts.Deep && isAPIStatusOK(result.APIStatus) {
+			if timeout <= 0 {
+				timeout = defaultDeepTimeout
+			}
+			deep, err := newRequester(endpoint, timeout, retries)
+			if err != nil {
+				result.Valid = false
+				result.Errors = append(result.Errors, fmt.Sprintf("Invalid provider configuration: %v", err))
+				return result
+			}
+			deepCheck(result, deep)
+		}
 	}
 
 	return result
 }
 
-// testAPIConnection tests if the API endpoint is reachable.
-// If model is configured, tests with /v1/messages. Otherwise, tests with /v1/models.
-func testAPIConnection(baseURL, authToken, model string) string {
-	client := &http.Client{
-		Timeout: 8 * time.Second,
+// configuredModels returns the model env keys that are set: ANTHROPIC_MODEL,
+// ANTHROPIC_SMALL_FAST_MODEL and ANTHROPIC_DEFAULT_*_MODEL, in that order.
+func configuredModels(env map[string]interface{}) []string {
+	var defaults []string
+	for key, value := range env {
+		if s, ok := value.(string); ok && s != "" &&
+			strings.HasPrefix(key, "ANTHROPIC_DEFAULT_") && strings.HasSuffix(key, "_MODEL") {
+			defaults = append(defaults, key)
+		}
 	}
+	sort.Strings(defaults)
 
-	// If no model specified, validate by fetching models list
-	if model == "" {
-		_, err := fetchAvailableModels(baseURL, authToken)
-		if err != nil {
-			return fmt.Sprintf("failed: %v", err)
+	var keys []string
+	for _, key := range []string{"ANTHROPIC_MODEL", "ANTHROPIC_SMALL_FAST_MODEL"} {
+		if s, ok := env[key].(string); ok && s != "" {
+			keys = append(keys, key)
 		}
-		// Successfully fetched models - token is valid
-		return "ok"
 	}
+	return append(keys, defaults...)
+}
 
-	// Model is configured, test with /v1/messages endpoint
-	// Note: Do not include beta=true query parameter, but beta headers are
-	// required by some providers (like 88) to identify Claude Code requests
-	messagesURL := strings.TrimSuffix(baseURL, "/") + "/v1/messages"
-	body := fmt.Sprintf(`{"model":"%s","max_tokens":10,"messages":[{"role":"user","content":"1+1=?"}]}`, model)
+// checkModels returns a warning for every configured model that is not in models,
+// with the closest available model as a suggestion.
+func checkModels(keys []string, env map[string]interface{}, models []string) []string {
+	var warnings []string
+	for _, key := range keys {
+		model := env[key].(string)
+		if containsModel(models, model) {
+			continue
+		}
+		warning := fmt.Sprintf("%s '%s' is not in the provider's model list", key, model)
+		if match := suggest.Closest(model, models); match != "" {
+			warning += fmt.Sprintf(", did you mean '%s'?", match)
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// containsModel reports whether models contains model.
+func containsModel(models []string, model string) bool {
+	for _, m := range models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
 
-	req, err := http.NewRequest("POST", messagesURL, strings.NewReader(body))
+// validateFailoverGroup checks that a failover group lists existing,
+// non-group providers, by name or alias. Members are validated individually
+// with --all.
+func validateFailoverGroup(cfg Config, result *ValidationResult, members interface{}) {
+	list, ok := members.([]interface{})
+	if !ok || len(list) == 0 {
+		result.Valid = false
+		result.Errors = append(result.Errors, "failover must be a non-empty list of provider names")
+		return
+	}
+
+	providers := cfg.Providers()
+	for _, m := range list {
+		name, ok := m.(string)
+		if !ok || name == "" {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("Invalid failover member: %v", m))
+			continue
+		}
+		name = cfg.ResolveAlias(name)
+		if _, exists := providers[name]; !exists {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("Failover member '%s' not found in configuration", name))
+			continue
+		}
+		if cfg.IsFailoverGroup(name) {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("Failover member '%s' is itself a failover group", name))
+		}
+	}
+}
+
+// NewMessagesRequest creates a POST /v1/messages request with the headers
+// Claude Code sends, so that it is accepted by third-party providers,
+// followed by the endpoint's credential and custom headers.
+func NewMessagesRequest(ctx context.Context, endpoint Endpoint, body []byte) (*http.Request, error) {
+	messagesURL := strings.TrimSuffix(endpoint.BaseURL, "/") + "/v1/messages"
+	req, err := http.NewRequestWithContext(ctx, "POST", messagesURL, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Sprintf("failed: %v", err)
+		return nil, err
 	}
 
 	// Use standard headers compatible with third-party providers
 	// Include beta headers for providers that require them (like 88) to identify Claude Code requests
-	req.Header.Set("Authorization", "Bearer "+authToken)
 	req.Header.Set("anthropic-version", "2023-06-01")
 	req.Header.Set("content-type", "application/json")
 	req.Header.Set("accept", "application/json")
@@ -272,8 +479,31 @@ func testAPIConnection(baseURL, authToken, model string) string {
 	req.Header.Set("x-app", "cli")
 	req.Header.Set("anthropic-beta", "claude-code-20250219,interleaved-thinking-2025-05-14")
 	req.Header.Set("anthropic-dangerous-direct-browser-access", "true")
+	endpoint.setHeaders(req)
+	return req, nil
+}
 
-	resp, err := client.Do(req)
+// testAPIConnection tests if the API endpoint is reachable.
+// If model is configured, tests with /v1/messages. Otherwise, tests with /v1/models.
+func testAPIConnection(r *requester, model string) string {
+	// If no model specified, validate by fetching models list
+	if model == "" {
+		_, err := r.fetchModels()
+		if err != nil {
+			return fmt.Sprintf("failed: %v", err)
+		}
+		// Successfully fetched models - token is valid
+		return "ok"
+	}
+
+	// Model is configured, test with /v1/messages endpoint
+	// Note: Do not include beta=true query parameter, but beta headers are
+	// required by some providers (like 88) to identify Claude Code requests
+	body := fmt.Sprintf(`{"model":"%s","max_tokens":10,"messages":[{"role":"user","content":"1+1=?"}]}`, model)
+
+	resp, err := r.do(func() (*http.Request, error) {
+		return NewMessagesRequest(context.Background(), r.endpoint, []byte(body))
+	})
 	if err != nil {
 		return fmt.Sprintf("failed: %v", err)
 	}
@@ -296,72 +526,34 @@ func testAPIConnection(baseURL, authToken, model string) string {
 
 // ValidateAllProviders validates all configured providers in parallel.
 func ValidateAllProviders(cfg Config) *ValidationSummary {
-	providers := cfg.Providers()
-	summary := &ValidationSummary{
-		Total:   len(providers),
-		Results: make([]*ValidationResult, 0, len(providers)),
+	return validateAllProviders(cfg, &RunOptions{})
+}
+
+// validateAllProviders validates all configured providers in parallel with the given options,
+// at most opts.Concurrency at a time. Results are in declaration order.
+func validateAllProviders(cfg Config, opts *RunOptions) *ValidationSummary {
+	names := cfg.ProviderNames()
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 || concurrency > len(names) {
+		concurrency = len(names)
 	}
 
+	results := make([]*ValidationResult, len(names))
+	sem := make(chan struct{}, max(concurrency, 1))
 	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	for providerName := range providers {
+	for i, name := range names {
 		wg.Add(1)
-		go func(name string) {
+		sem <- struct{}{}
+		go func(i int, name string) {
 			defer wg.Done()
-			result := ValidateProvider(cfg, name)
-
-			mu.Lock()
-			summary.Results = append(summary.Results, result)
-
-			if result.Valid {
-				summary.Valid++
-			} else {
-				summary.Invalid++
-			}
-
-			if result.APIStatus != "" && !isAPIStatusOK(result.APIStatus) {
-				summary.Warning++
-			}
-			mu.Unlock()
-		}(providerName)
+			defer func() { <-sem }()
+			results[i] = validateProvider(cfg, name, opts)
+		}(i, name)
 	}
 
 	wg.Wait()
-	return summary
-}
-
-// PrintResult prints a single validation result with color coding.
-func PrintResult(result *ValidationResult) {
-	status := "Valid"
-	statusColor := "\033[32m" // green
-	if !result.Valid {
-		status = "Invalid"
-		statusColor = "\033[31m" // red
-	} else if result.APIStatus != "" && !isAPIStatusOK(result.APIStatus) {
-		status = "Warning"
-		statusColor = "\033[33m" // yellow
-	}
-
-	fmt.Printf("  %s%s\033[0m: %s\n", statusColor, status, result.Provider)
-
-	if result.BaseURL != "" {
-		fmt.Printf("    Base URL: %s\n", result.BaseURL)
-	}
-	if result.Model != "" {
-		fmt.Printf("    Model: %s\n", result.Model)
-	}
-	if result.APIStatus != "" {
-		apiStatus, apiColor := formatAPIStatus(result.APIStatus)
-		fmt.Printf("    API connection: %s%s\033[0m\n", apiColor, apiStatus)
-	}
-
-	for _, warning := range result.Warnings {
-		fmt.Printf("    Warning: %s\n", warning)
-	}
-	for _, err := range result.Errors {
-		fmt.Printf("    Error: %s\n", err)
-	}
+	return summarize(results)
 }
 
 // isAPIStatusOK checks if the API status indicates a successful validation.
@@ -369,79 +561,125 @@ func isAPIStatusOK(status string) bool {
 	return status == "ok"
 }
 
-// formatAPIStatus formats the API status for display, returning the display text and color.
-func formatAPIStatus(status string) (string, string) {
-	if status == "ok" {
-		return "OK", "\033[32m"
-	}
-	return status, "\033[33m"
-}
+// summarize counts valid, invalid and warning results.
+func summarize(results []*ValidationResult) *ValidationSummary {
+	summary := &ValidationSummary{
+		Total:   len(results),
+		Results: results,
+	}
+	for _, result := range results {
+		if result.Valid {
+			summary.Valid++
+		} else {
+			summary.Invalid++
+		}
 
-// PrintSummary prints the validation summary for all providers.
-func PrintSummary(summary *ValidationSummary) {
-	fmt.Println()
-	if summary.Invalid > 0 {
-		fmt.Printf("\033[31m%d/%d\033[0m providers invalid\n", summary.Invalid, summary.Total)
-	} else if summary.Warning > 0 {
-		fmt.Printf("\033[33mAll providers valid (%d with API warnings)\033[0m\n", summary.Warning)
-	} else {
-		fmt.Println("\033[32mAll providers valid\033[0m")
+		if result.Status() == "warning" {
+			summary.Warning++
+		}
 	}
+	return summary
 }
 
 // RunOptions represents the options for running validation.
 type RunOptions struct {
 	Provider    string // Empty means current provider
 	ValidateAll bool
+	Format      string // text (default), json or junit
+	Deep        bool   // Also check streaming, tool use, thinking and images
+
+	Timeout     time.Duration // Timeout of each request, zero means the default
+	Retries     int           // Retries of failed requests (network errors, HTTP 429 and 5xx)
+	Concurrency int           // Providers validated at once with ValidateAll, zero means no limit
+
+	// TimeoutSet and RetriesSet mark Timeout and Retries as given explicitly,
+	// e.g. on the command line, so they override the provider's validate settings
+	TimeoutSet bool
+	RetriesSet bool
+
+	// Report, if set, is called with the results once validation is done, e.g. to record them
+	Report func(results []*ValidationResult)
 }
 
 // Run executes the validation command with the given options.
+// Results are written to stdout in the requested format.
 func Run(cfg Config, opts *RunOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = FormatText
+	}
+	if !isValidFormat(format) {
+		return fmt.Errorf("unknown format '%s' (supported: %s)", format, strings.Join(Formats, ", "))
+	}
+	if opts.Timeout < 0 || opts.Retries < 0 || opts.Concurrency < 0 {
+		return fmt.Errorf("--timeout, --retries and --concurrency must not be negative")
+	}
+	text := format == FormatText
+
 	// Handle validate all
 	if opts.ValidateAll {
 		if len(cfg.Providers()) == 0 {
-			fmt.Println("No providers configured")
-			return nil
+			if text {
+				fmt.Println("No providers configured")
+				return nil
+			}
+			return WriteReport(os.Stdout, format, &ValidationSummary{Results: []*ValidationResult{}})
 		}
 
-		fmt.Printf("Validating %d provider(s)...\n\n", len(cfg.Providers()))
-		summary := ValidateAllProviders(cfg)
-
-		for _, result := range summary.Results {
-			PrintResult(result)
+		if text {
+			fmt.Printf("Validating %d provider(s)...\n\n", len(cfg.Providers()))
+		}
+		summary := validateAllProviders(cfg, opts)
+		summary.Errors = cfg.CheckAliases("")
+		if opts.Report != nil {
+			opts.Report(summary.Results)
 		}
 
-		PrintSummary(summary)
+		if err := WriteReport(os.Stdout, format, summary); err != nil {
+			return err
+		}
 
 		// Return error if any provider is invalid or API test failed
 		if summary.Invalid > 0 {
 			return fmt.Errorf("%d provider(s) invalid", summary.Invalid)
 		}
+		if len(summary.Errors) > 0 {
+			return fmt.Errorf("invalid configuration: %s", strings.Join(summary.Errors, "; "))
+		}
 		if summary.Warning > 0 {
-			return fmt.Errorf("%d provider(s) with API failures", summary.Warning)
+			return fmt.Errorf("%d provider(s) with API or deep check failures", summary.Warning)
 		}
 		return nil
 	}
 
 	// Determine which provider to validate
-	providerName := opts.Provider
+	providerName := cfg.ResolveAlias(opts.Provider)
 	if providerName == "" {
 		providerName = cfg.CurrentProvider()
 	}
 
 	if providerName == "" {
-		fmt.Println("No current provider set")
-		if len(cfg.Providers()) > 0 {
-			fmt.Println("\nAvailable providers:")
-			for name := range cfg.Providers() {
-				fmt.Printf("  %s\n", name)
+		if text {
+			fmt.Println("No current provider set")
+			if len(cfg.Providers()) > 0 {
+				fmt.Println("\nAvailable providers:")
+				for _, name := range cfg.ProviderNames() {
+					fmt.Printf("  %s\n", name)
+				}
 			}
 		}
 		return fmt.Errorf("no provider specified")
 	}
 
-	result := ValidateProvider(cfg, providerName)
-	PrintResult(result)
+	result := validateProvider(cfg, providerName, opts)
+	if opts.Report != nil {
+		opts.Report([]*ValidationResult{result})
+	}
+	if text {
+		PrintResult(result)
+	} else if err := WriteReport(os.Stdout, format, summarize([]*ValidationResult{result})); err != nil {
+		return err
+	}
 
 	if !result.Valid {
 		return fmt.Errorf("provider '%s' is invalid", providerName)
@@ -451,6 +689,9 @@ func Run(cfg Config, opts *RunOptions) error {
 	if result.APIStatus != "" && !isAPIStatusOK(result.APIStatus) {
 		return fmt.Errorf("provider '%s' API test failed: %s", providerName, result.APIStatus)
 	}
+	if result.Capabilities != nil && !result.Capabilities.Required() {
+		return fmt.Errorf("provider '%s' failed the deep checks: %s", providerName, result.Capabilities)
+	}
 
 	return nil
 }
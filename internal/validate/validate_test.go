@@ -1,28 +1,66 @@
 package validate
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
 	"testing"
+
+	"github.com/guyskk/ccc/internal/config"
 )
 
 // mockConfig implements Config interface for testing.
 type mockConfig struct {
 	providers       map[string]map[string]interface{}
 	currentProvider string
+	resolveErrors   map[string]error
+	aliasProblems   []string // Returned by CheckAliases("")
 }
 
 func (m *mockConfig) Providers() map[string]map[string]interface{} {
 	return m.providers
 }
 
+func (m *mockConfig) ProviderNames() []string {
+	names := make([]string, 0, len(m.providers))
+	for name := range m.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (m *mockConfig) CurrentProvider() string {
 	return m.currentProvider
 }
 
+func (m *mockConfig) ResolveProvider(name string) (map[string]interface{}, error) {
+	if err, ok := m.resolveErrors[name]; ok {
+		return nil, err
+	}
+	return m.providers[name], nil
+}
+
+func (m *mockConfig) IsFailoverGroup(name string) bool {
+	_, isGroup := m.providers[name]["failover"]
+	return isGroup
+}
+
+func (m *mockConfig) ResolveAlias(name string) string {
+	return name
+}
+
+func (m *mockConfig) CheckAliases(name string) []string {
+	if name == "" {
+		return m.aliasProblems
+	}
+	return nil
+}
+
 func TestValidateProvider(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -186,6 +224,60 @@ func TestValidateProvider(t *testing.T) {
 			wantValid: true,
 			wantErrs:  nil,
 		},
+		{
+			name: "provider that cannot be resolved",
+			config: &mockConfig{
+				providers: map[string]map[string]interface{}{
+					"loop": {"extends": "loop"},
+				},
+				resolveErrors: map[string]error{
+					"loop": fmt.Errorf("provider inheritance cycle: loop -> loop"),
+				},
+			},
+			provider:  "loop",
+			wantValid: false,
+			wantErrs:  []string{"Invalid provider configuration: provider inheritance cycle: loop -> loop"},
+		},
+		{
+			name: "failover group with valid members",
+			config: &mockConfig{
+				providers: map[string]map[string]interface{}{
+					"kimi": {},
+					"glm":  {},
+					"auto": {"failover": []interface{}{"kimi", "glm"}},
+				},
+			},
+			provider:  "auto",
+			wantValid: true,
+			wantErrs:  nil,
+		},
+		{
+			name: "failover group with unknown and nested members",
+			config: &mockConfig{
+				providers: map[string]map[string]interface{}{
+					"kimi":   {},
+					"auto":   {"failover": []interface{}{"kimi"}},
+					"broken": {"failover": []interface{}{"auto", "missing"}},
+				},
+			},
+			provider:  "broken",
+			wantValid: false,
+			wantErrs: []string{
+				"Failover member 'auto' is itself a failover group",
+				"Failover member 'missing' not found in configuration",
+			},
+		},
+		{
+			name: "empty failover group",
+			config: &mockConfig{
+				providers: map[string]map[string]interface{}{
+					"auto": {"failover": []interface{}{}},
+				},
+			},
+			provider:  "auto",
+			wantValid: false,
+			wantErrs:  []string{"failover must be a non-empty list"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -341,6 +433,38 @@ func TestValidationResultFields(t *testing.T) {
 	}
 }
 
+func TestValidationResultAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid token"}`)
+	}))
+	defer server.Close()
+
+	config := &mockConfig{
+		providers: map[string]map[string]interface{}{
+			"test": {
+				"env": map[string]interface{}{
+					"ANTHROPIC_BASE_URL":   server.URL,
+					"ANTHROPIC_AUTH_TOKEN": "sk-test-token",
+					"ANTHROPIC_MODEL":      "m",
+				},
+			},
+		},
+	}
+
+	result := ValidateProvider(config, "test")
+	if result.APIError == nil || !strings.Contains(result.APIError.Error(), "HTTP 401") {
+		t.Fatalf("APIError = %v, want the HTTP 401 failure", result.APIError)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"api_error":"HTTP 401`) {
+		t.Errorf("JSON = %s, want api_error", data)
+	}
+}
+
 func TestRun(t *testing.T) {
 	t.Run("validate all - all valid", func(t *testing.T) {
 		config := &mockConfig{
@@ -526,14 +650,14 @@ func TestPrintSummary(t *testing.T) {
 // Test testAPIConnection with various scenarios
 func TestTestAPIConnection(t *testing.T) {
 	t.Run("invalid URL format", func(t *testing.T) {
-		status := testAPIConnection("://invalid-url", "sk-test", "")
+		status := testAPIConnection(testRequester(t, "://invalid-url", 0, 0), "")
 		if !strings.Contains(status, "failed") {
 			t.Errorf("testAPIConnection() = %q, want contains 'failed'", status)
 		}
 	})
 
 	t.Run("unreachable URL", func(t *testing.T) {
-		status := testAPIConnection("http://localhost:9999/anthropic", "sk-test", "")
+		status := testAPIConnection(testRequester(t, "http://localhost:9999/anthropic", 0, 0), "")
 		if !strings.Contains(status, "failed") {
 			t.Errorf("testAPIConnection() = %q, want contains 'failed'", status)
 		}
@@ -587,7 +711,7 @@ func TestFormatAPIStatus(t *testing.T) {
 	}
 }
 
-// Test selectBestModel function
+// Test SelectBestModel function
 func TestSelectBestModel(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -644,8 +768,8 @@ func TestSelectBestModel(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := selectBestModel(tt.models); got != tt.want {
-				t.Errorf("selectBestModel() = %q, want %q", got, tt.want)
+			if got := SelectBestModel(tt.models); got != tt.want {
+				t.Errorf("SelectBestModel() = %q, want %q", got, tt.want)
 			}
 		})
 	}
@@ -718,10 +842,10 @@ func TestRequestHeaders(t *testing.T) {
 		}))
 		defer server.Close()
 
-		// Call fetchAvailableModels which will hit our test server
-		models, err := fetchAvailableModels(server.URL, "test-token")
+		// Call FetchModels which will hit our test server
+		models, err := FetchModels(Endpoint{BaseURL: server.URL, AuthToken: "test-token"})
 		if err != nil {
-			t.Fatalf("fetchAvailableModels failed: %v", err)
+			t.Fatalf("FetchModels failed: %v", err)
 		}
 
 		// Verify we got a response
@@ -775,7 +899,11 @@ func TestRequestHeaders(t *testing.T) {
 		defer server.Close()
 
 		// Call testAPIConnection which will hit our test server
-		status := testAPIConnection(server.URL, "test-token", "claude-3-5-sonnet-20241022")
+		r, err := newRequester(Endpoint{BaseURL: server.URL, AuthToken: "test-token"}, 0, 0)
+		if err != nil {
+			t.Fatalf("newRequester() error = %v", err)
+		}
+		status := testAPIConnection(r, "claude-3-5-sonnet-20241022")
 
 		// Verify we got a successful response
 		if status != "ok" {
@@ -818,3 +946,150 @@ func TestRequestHeaders(t *testing.T) {
 		}
 	})
 }
+
+// TestRunConfigErrors verifies that problems belonging to no provider fail
+// validate --all even when every provider is valid.
+func TestRunConfigErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	cfg := newMockConfig(map[string]map[string]interface{}{
+		"kimi": {"env": map[string]interface{}{
+			"ANTHROPIC_BASE_URL":   server.URL,
+			"ANTHROPIC_AUTH_TOKEN": "test-token",
+		}},
+	}, "kimi")
+	cfg.aliasProblems = []string{"alias 'gone' refers to missing provider 'deleted'"}
+
+	var summary *ValidationSummary
+	opts := &RunOptions{ValidateAll: true, Format: FormatJSON, Report: func(results []*ValidationResult) {
+		summary = summarize(results)
+	}}
+	err := Run(cfg, opts)
+	if err == nil || !strings.Contains(err.Error(), "refers to missing provider 'deleted'") {
+		t.Errorf("Run() error = %v, want the dangling alias", err)
+	}
+	if summary == nil || summary.Invalid != 0 {
+		t.Errorf("summary = %+v, want kimi valid", summary)
+	}
+
+	cfg.aliasProblems = nil
+	if err := Run(cfg, opts); err != nil {
+		t.Errorf("Run() error = %v, want nil", err)
+	}
+}
+
+// TestValidateProviderModels verifies that configured models are checked
+// against /v1/models, with suggestions for close matches.
+func TestValidateProviderModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/v1/models" {
+			fmt.Fprint(w, `{"data":[{"id":"kimi-k2-thinking"},{"id":"kimi-k2-0905-preview"},{"id":"kimi-latest"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	cfg := newMockConfig(map[string]map[string]interface{}{
+		"kimi": {"env": map[string]interface{}{
+			"ANTHROPIC_BASE_URL":             server.URL,
+			"ANTHROPIC_AUTH_TOKEN":           "test-token",
+			"ANTHROPIC_MODEL":                "kimi-k2-thinkng",
+			"ANTHROPIC_SMALL_FAST_MODEL":     "kimi-k2-0905-preview",
+			"ANTHROPIC_DEFAULT_OPUS_MODEL":   "gpt-5",
+			"ANTHROPIC_DEFAULT_SONNET_MODEL": "kimi-k2-thinking",
+		}},
+	}, "kimi")
+
+	result := ValidateProvider(cfg, "kimi")
+	if !result.Valid || result.APIStatus != "ok" {
+		t.Fatalf("Valid = %v, APIStatus = %q, errors = %v", result.Valid, result.APIStatus, result.Errors)
+	}
+	want := []string{
+		"ANTHROPIC_MODEL 'kimi-k2-thinkng' is not in the provider's model list, did you mean 'kimi-k2-thinking'?",
+		"ANTHROPIC_DEFAULT_OPUS_MODEL 'gpt-5' is not in the provider's model list",
+	}
+	if strings.Join(result.Warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Warnings = %q, want %q", result.Warnings, want)
+	}
+
+	// Providers without a /v1/models endpoint are not checked
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/models" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer plain.Close()
+	cfg.providers["kimi"]["env"].(map[string]interface{})["ANTHROPIC_BASE_URL"] = plain.URL
+	if result := ValidateProvider(cfg, "kimi"); len(result.Warnings) != 0 {
+		t.Errorf("Warnings = %q, want none", result.Warnings)
+	}
+}
+
+func TestValidateProviderCredentials(t *testing.T) {
+	var auth, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, apiKey = r.Header.Get("Authorization"), r.Header.Get("x-api-key")
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer server.Close()
+
+	original := config.RunAPIKeyHelper
+	defer func() { config.RunAPIKeyHelper = original }()
+	config.RunAPIKeyHelper = func(command string) (string, error) {
+		if command == "fail" {
+			return "", fmt.Errorf("exit status 1")
+		}
+		return "sk-helper", nil
+	}
+
+	tests := []struct {
+		name        string
+		provider    map[string]interface{}
+		wantAuth    string
+		wantAPIKey  string
+		wantWarning string
+		wantErr     string
+	}{
+		{"auth token", map[string]interface{}{"env": map[string]interface{}{"ANTHROPIC_AUTH_TOKEN": "sk-t"}}, "Bearer sk-t", "", "", ""},
+		{"api key", map[string]interface{}{"env": map[string]interface{}{"ANTHROPIC_API_KEY": "sk-k"}}, "", "sk-k", "", ""},
+		{
+			"both",
+			map[string]interface{}{"env": map[string]interface{}{"ANTHROPIC_AUTH_TOKEN": "sk-t", "ANTHROPIC_API_KEY": "sk-k"}},
+			"Bearer sk-t", "sk-k", "Both ANTHROPIC_AUTH_TOKEN and ANTHROPIC_API_KEY are set", "",
+		},
+		{"api key helper", map[string]interface{}{"apiKeyHelper": "print-key", "env": map[string]interface{}{}}, "Bearer sk-helper", "sk-helper", "", ""},
+		{"failing helper", map[string]interface{}{"apiKeyHelper": "fail", "env": map[string]interface{}{}}, "", "", "", "apiKeyHelper failed"},
+		{"none", map[string]interface{}{"env": map[string]interface{}{}}, "", "", "", "ANTHROPIC_AUTH_TOKEN or ANTHROPIC_API_KEY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, apiKey = "", ""
+			tt.provider["env"].(map[string]interface{})["ANTHROPIC_BASE_URL"] = server.URL
+			result := ValidateProvider(newMockConfig(map[string]map[string]interface{}{"p": tt.provider}, "p"), "p")
+
+			if tt.wantErr != "" {
+				if result.Valid || !strings.Contains(strings.Join(result.Errors, "\n"), tt.wantErr) {
+					t.Errorf("Errors = %q, want %q", result.Errors, tt.wantErr)
+				}
+				return
+			}
+			if !result.Valid || result.APIStatus != "ok" {
+				t.Fatalf("Valid = %v, APIStatus = %q, errors = %v", result.Valid, result.APIStatus, result.Errors)
+			}
+			if auth != tt.wantAuth || apiKey != tt.wantAPIKey {
+				t.Errorf("Authorization = %q, x-api-key = %q, want %q, %q", auth, apiKey, tt.wantAuth, tt.wantAPIKey)
+			}
+			if warnings := strings.Join(result.Warnings, "\n"); !strings.Contains(warnings, tt.wantWarning) || (tt.wantWarning == "" && warnings != "") {
+				t.Errorf("Warnings = %q, want %q", result.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}